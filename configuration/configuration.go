@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/go-openapi/strfmt"
 	parser "github.com/haproxytech/config-parser/v3"
 	"github.com/haproxytech/config-parser/v3/common"
 	parser_errors "github.com/haproxytech/config-parser/v3/errors"
@@ -67,6 +68,10 @@ type ClientParams struct {
 	// ValidateCmd allows specifying a custom script to validate the transaction file.
 	// The injected environment variable DATAPLANEAPI_TRANSACTION_FILE must be used to get the location of the file.
 	ValidateCmd string
+
+	// MaxVersionDrift is how far the configured version may advance past the version
+	// of a transaction before its commit is rejected, 0 requires them to be equal.
+	MaxVersionDrift int64
 }
 
 // Client configuration client
@@ -169,6 +174,9 @@ func (c *Client) GetParserTransactions() models.Transactions {
 				Status:  models.TransactionStatusInProgress,
 				Version: v,
 			}
+			if expiresAt, ok := c.GetTransactionExpiry(tID); ok {
+				t.ExpiresAt = strfmt.DateTime(expiresAt)
+			}
 			transactions = append(transactions, t)
 		}
 	}
@@ -32,6 +32,9 @@ const (
 	ErrNoVersionTransaction   = 13
 	ErrValidationError        = 14
 	ErrVersionMismatch        = 15
+	ErrUnknownDirective       = 16
+	ErrSectionLimitExceeded   = 17
+	ErrVersionConflict        = 18
 
 	ErrTransactionDoesNotExist  = 20
 	ErrTransactionAlreadyExists = 21
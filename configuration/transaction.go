@@ -26,7 +26,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/google/uuid"
 	parser "github.com/haproxytech/config-parser/v3"
 	parser_errors "github.com/haproxytech/config-parser/v3/errors"
@@ -54,10 +56,15 @@ type TransactionClient interface {
 // actually implemented moving to the `failed` or `outdated` folder.
 type transactionCleanerHandler func(transactionId, configurationFile string)
 
+// Transaction handles transactions of a TransactionClient, mu serializes commits.
+// ttlMu guards ttl and expiries, the deadlines of transactions in progress.
 type Transaction struct {
 	mu sync.Mutex
 	ClientParams
 	TransactionClient TransactionClient
+	ttlMu             sync.Mutex
+	ttl               time.Duration
+	expiries          map[string]*transactionExpiry
 }
 
 // GetTransactions returns an array of transactions
@@ -78,7 +85,11 @@ func (t *Transaction) GetTransaction(transactionID string) (*models.Transaction,
 	}
 	v, _ := t.TransactionClient.GetVersion(transactionID)
 
-	return &models.Transaction{ID: transactionID, Status: models.TransactionStatusInProgress, Version: v}, nil
+	m := &models.Transaction{ID: transactionID, Status: models.TransactionStatusInProgress, Version: v}
+	if expiresAt, ok := t.GetTransactionExpiry(transactionID); ok {
+		m.ExpiresAt = strfmt.DateTime(expiresAt)
+	}
+	return m, nil
 }
 
 // StartTransaction starts a new empty lbctl transaction
@@ -118,6 +129,10 @@ func (t *Transaction) startTransaction(version int64, skipVersion bool) (*models
 		}
 		return nil, err
 	}
+	t.touchTransaction(m.ID)
+	if expiresAt, ok := t.GetTransactionExpiry(m.ID); ok {
+		m.ExpiresAt = strfmt.DateTime(expiresAt)
+	}
 	return m, nil
 }
 
@@ -144,9 +159,8 @@ func (t *Transaction) commitTransaction(transactionID string, skipVersion bool)
 	}
 
 	if !skipVersion {
-		if tVersion != version {
-			t.failTransaction(transactionID, t.writeOutdatedTransaction)
-			return nil, NewConfError(ErrVersionMismatch, fmt.Sprintf("version mismatch, transaction version: %v, configured version: %v", tVersion, version))
+		if err := t.checkVersionDrift(transactionID, tVersion, version); err != nil {
+			return nil, err
 		}
 	}
 
@@ -172,8 +186,12 @@ func (t *Transaction) commitTransaction(transactionID string, skipVersion bool)
 	}
 
 	if !skipVersion {
-		if err := t.TransactionClient.IncrementTransactionVersion(transactionID); err != nil {
-			return nil, err
+		// a transaction behind the configured version within the allowed drift
+		// is committed as the version following the configured one
+		for v := tVersion; v <= version; v++ {
+			if err := t.TransactionClient.IncrementTransactionVersion(transactionID); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -182,13 +200,7 @@ func (t *Transaction) commitTransaction(transactionID string, skipVersion bool)
 		return nil, err
 	}
 
-	// Fail backing up and cleaning backups silently
-	if t.BackupsNumber > 0 {
-		backupConfFile := fmt.Sprintf("%v.%v", t.ConfigurationFile, strconv.Itoa(int(version)))
-		_ = t.TransactionClient.Save(backupConfFile, "")
-		backupToDel := fmt.Sprintf("%v.%v", t.ConfigurationFile, strconv.Itoa(int(version)-t.BackupsNumber))
-		os.Remove(backupToDel)
-	}
+	t.BackupConfiguration(version)
 
 	if err := t.TransactionClient.Save(t.ConfigurationFile, transactionID); err != nil {
 		t.failTransaction(transactionID, t.writeFailedTransaction)
@@ -201,10 +213,48 @@ func (t *Transaction) commitTransaction(transactionID string, skipVersion bool)
 		_ = t.TransactionClient.LoadData(t.ConfigurationFile)
 		return nil, err
 	}
+	t.forgetTransaction(transactionID)
 
 	return &models.Transaction{ID: transactionID, Version: tVersion, Status: "success"}, nil
 }
 
+// BackupConfiguration saves the configuration at version as <configuration file>.<version>
+// and removes the backup BackupsNumber versions older. Does nothing if BackupsNumber is 0,
+// fails silently. Must be called from WithCommitLock when not committing.
+func (t *Transaction) BackupConfiguration(version int64) {
+	if t.BackupsNumber <= 0 {
+		return
+	}
+	backupConfFile := fmt.Sprintf("%v.%v", t.ConfigurationFile, strconv.Itoa(int(version)))
+	_ = t.TransactionClient.Save(backupConfFile, "")
+	backupToDel := fmt.Sprintf("%v.%v", t.ConfigurationFile, strconv.Itoa(int(version)-t.BackupsNumber))
+	os.Remove(backupToDel)
+}
+
+// WithCommitLock runs f holding the lock commits hold, for changes made to the
+// configuration file outside of transactions
+func (t *Transaction) WithCommitLock(f func() error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return f()
+}
+
+// checkVersionDrift fails and moves the transaction to outdated if its version
+// differs from the configured version, or is behind it by more than MaxVersionDrift
+func (t *Transaction) checkVersionDrift(transactionID string, tVersion, version int64) error {
+	if tVersion == version {
+		return nil
+	}
+	if tVersion < version && version-tVersion <= t.MaxVersionDrift {
+		return nil
+	}
+	t.failTransaction(transactionID, t.writeOutdatedTransaction)
+	if t.MaxVersionDrift > 0 {
+		return NewConfError(ErrVersionConflict, fmt.Sprintf("version conflict, transaction version: %v, configured version: %v, allowed drift: %v", tVersion, version, t.MaxVersionDrift))
+	}
+	return NewConfError(ErrVersionMismatch, fmt.Sprintf("version mismatch, transaction version: %v, configured version: %v", tVersion, version))
+}
+
 func (t *Transaction) checkTransactionFile(transactionID string) error {
 	// check only against HAProxy file
 	_, ok := t.TransactionClient.(*Client)
@@ -367,6 +417,7 @@ func (t *Transaction) DeleteTransaction(transactionID string) error {
 	if transactionID == "" {
 		return nil
 	}
+	t.forgetTransaction(transactionID)
 
 	if t.PersistentTransactions {
 		if err := t.deleteTransactionFiles(transactionID); err != nil {
@@ -577,6 +628,7 @@ func (t *Transaction) failTransaction(transactionID string, txHandler transactio
 		txHandler(transactionID, configFile)
 	}
 	_ = t.TransactionClient.DeleteParser(transactionID)
+	t.forgetTransaction(transactionID)
 }
 
 func (t *Transaction) writeOutdatedTransaction(transactionID, configFile string) {
@@ -638,11 +690,10 @@ func (t *Transaction) SaveData(prsr interface{}, tID string, commitImplicit bool
 		if err != nil {
 			return err
 		}
+		// saved by the client, which may add headers to the transaction file
 		switch p := prsr.(type) {
-		case *spoe.Parser:
-			err = p.Save(tFile)
-		case *parser.Parser:
-			err = p.Save(tFile)
+		case *spoe.Parser, *parser.Parser:
+			err = t.TransactionClient.Save(tFile, tID)
 		default:
 			return fmt.Errorf("provided parser %s not supported", p)
 		}
@@ -658,7 +709,9 @@ func (t *Transaction) SaveData(prsr interface{}, tID string, commitImplicit bool
 		if _, err := t.CommitTransaction(tID); err != nil {
 			return err
 		}
+		return nil
 	}
+	t.touchTransaction(tID)
 	return nil
 }
 
@@ -0,0 +1,113 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package configuration
+
+import (
+	"time"
+)
+
+// transactionExpiry is the deadline of a transaction in progress and the timer
+// deleting it once the deadline passes
+type transactionExpiry struct {
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// SetTransactionTTL sets how long a transaction in progress is kept without being
+// written to. Transactions started or written after the call are deleted once
+// d passes without a write, each write resets the deadline. A d of 0 or less
+// disables expiry and stops pending deadlines, as StopReaper does.
+func (t *Transaction) SetTransactionTTL(d time.Duration) {
+	if d <= 0 {
+		t.StopReaper()
+		return
+	}
+	t.ttlMu.Lock()
+	t.ttl = d
+	t.ttlMu.Unlock()
+}
+
+// StopReaper disables transaction expiry and stops all pending deadlines,
+// transactions in progress are kept
+func (t *Transaction) StopReaper() {
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	t.ttl = 0
+	for _, e := range t.expiries {
+		e.timer.Stop()
+	}
+	t.expiries = nil
+}
+
+// GetTransactionExpiry returns when a transaction in progress expires, false if
+// it has no deadline
+func (t *Transaction) GetTransactionExpiry(transactionID string) (time.Time, bool) {
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	e, ok := t.expiries[transactionID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return e.expiresAt, true
+}
+
+// touchTransaction starts or resets the deadline of a transaction when a TTL is set
+func (t *Transaction) touchTransaction(transactionID string) {
+	if transactionID == "" {
+		return
+	}
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	if t.ttl <= 0 {
+		return
+	}
+	if t.expiries == nil {
+		t.expiries = make(map[string]*transactionExpiry)
+	}
+	if e, ok := t.expiries[transactionID]; ok {
+		e.timer.Stop()
+	}
+	t.expiries[transactionID] = &transactionExpiry{
+		expiresAt: time.Now().Add(t.ttl),
+		timer:     time.AfterFunc(t.ttl, func() { t.reapTransaction(transactionID) }),
+	}
+}
+
+// forgetTransaction stops the deadline of a transaction which is no longer in progress
+func (t *Transaction) forgetTransaction(transactionID string) {
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	if e, ok := t.expiries[transactionID]; ok {
+		e.timer.Stop()
+		delete(t.expiries, transactionID)
+	}
+}
+
+// reapTransaction deletes a transaction whose deadline passed, unless it was
+// written, committed or deleted in the meantime
+func (t *Transaction) reapTransaction(transactionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ttlMu.Lock()
+	e, ok := t.expiries[transactionID]
+	if !ok || time.Now().Before(e.expiresAt) {
+		t.ttlMu.Unlock()
+		return
+	}
+	delete(t.expiries, transactionID)
+	t.ttlMu.Unlock()
+	_ = t.DeleteTransaction(transactionID)
+}
@@ -39,6 +39,10 @@ type Transaction struct {
 	// version
 	Version int64 `json:"_version,omitempty"`
 
+	// expires at
+	// Format: date-time
+	ExpiresAt strfmt.DateTime `json:"expires_at,omitempty"`
+
 	// id
 	// Pattern: ^[^\s]+$
 	ID string `json:"id,omitempty"`
@@ -52,6 +56,10 @@ type Transaction struct {
 func (m *Transaction) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateExpiresAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateID(formats); err != nil {
 		res = append(res, err)
 	}
@@ -66,6 +74,19 @@ func (m *Transaction) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Transaction) validateExpiresAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ExpiresAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("expires_at", "body", "date-time", m.ExpiresAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *Transaction) validateID(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.ID) { // not required
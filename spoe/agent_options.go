@@ -0,0 +1,430 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/common"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// SPOE capabilities HAProxy announces to the agent in the HELLO frame
+const (
+	CapabilityPipelining    = "pipelining"
+	CapabilityAsync         = "async"
+	CapabilityFragmentation = "fragmentation"
+)
+
+// capabilityOptions maps each capability to the agent option which controls it,
+// capabilities are announced when their option is enabled, which is the default
+var capabilityOptions = []struct { //nolint:gochecknoglobals
+	capability string
+	option     string
+}{
+	{CapabilityPipelining, "option pipelining"},
+	{CapabilityAsync, "option async"},
+	{CapabilityFragmentation, "option send-frag-payload"},
+}
+
+// GetSPOEAgentCapabilities returns the capabilities an agent announces.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentCapabilities(scope, agentName string, transactionID string) ([]string, error) {
+	caps := []string{}
+	for _, co := range capabilityOptions {
+		data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, co.option, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := data.(*types.SimpleOption); ok && !d.NoOption {
+			caps = append(caps, co.capability)
+		}
+	}
+	return caps, nil
+}
+
+// SetSPOEAgentCapabilities sets the capabilities an agent announces, capabilities
+// not listed in caps are disabled. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEAgentCapabilities(scope, agentName string, caps []string, transactionID string, version int64) error {
+	for _, capability := range caps {
+		if !isKnownCapability(capability) {
+			return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown capability %s", capability))
+		}
+	}
+
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		for _, co := range capabilityOptions {
+			d := &types.SimpleOption{NoOption: !misc.StringInSlice(co.capability, caps)}
+			if err := p.Set(scope, parser.SPOEAgent, agentName, co.option, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func isKnownCapability(capability string) bool {
+	for _, co := range capabilityOptions {
+		if co.capability == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSPOEAgentUseBackend returns the backend an agent uses to connect to SPOE servers,
+// empty string if not set. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentUseBackend(scope, agentName string, transactionID string) (string, error) {
+	data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, "use-backend", transactionID)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := data.(*types.StringC); ok {
+		return d.Value, nil
+	}
+	return "", nil
+}
+
+// SetSPOEAgentUseBackend sets the backend an agent uses to connect to SPOE servers,
+// empty backendName removes the directive. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEAgentUseBackend(scope, agentName, backendName string, transactionID string, version int64) error {
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		if backendName == "" {
+			return p.Set(scope, parser.SPOEAgent, agentName, "use-backend", nil)
+		}
+		return p.Set(scope, parser.SPOEAgent, agentName, "use-backend", &types.StringC{Value: backendName})
+	})
+}
+
+// varPrefixRegexp matches names HAProxy accepts as variable prefix
+var varPrefixRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`) //nolint:gochecknoglobals
+
+// GetSPOEAgentVarPrefix returns the prefix of variables set by an agent, empty
+// string if not set. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentVarPrefix(scope, agentName string, transactionID string) (string, error) {
+	data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, "option var-prefix", transactionID)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := data.(*types.StringC); ok {
+		return d.Value, nil
+	}
+	return "", nil
+}
+
+// SetSPOEAgentVarPrefix sets the prefix of variables set by an agent, empty prefix
+// removes the directive. One of version or transactionID is mandatory.
+// Returns error on fail or if prefix is not valid, nil on success.
+func (c *SingleSpoe) SetSPOEAgentVarPrefix(scope, agentName, prefix string, transactionID string, version int64) error {
+	if prefix != "" && !varPrefixRegexp.MatchString(prefix) {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("invalid var-prefix %s, must match %s", prefix, varPrefixRegexp.String()))
+	}
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		if prefix == "" {
+			return p.Set(scope, parser.SPOEAgent, agentName, "option var-prefix", nil)
+		}
+		return p.Set(scope, parser.SPOEAgent, agentName, "option var-prefix", &types.StringC{Value: prefix})
+	})
+}
+
+// varNameRegexp matches names HAProxy accepts as variable name
+var varNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._]+$`) //nolint:gochecknoglobals
+
+// GetSPOEAgentRegisterVars returns the names of variables an agent registers, empty
+// list if not set. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentRegisterVars(scope, agentName string, transactionID string) ([]string, error) {
+	data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, "register-var-names", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := data.(*types.StringC); ok {
+		return strings.Fields(d.Value), nil
+	}
+	return []string{}, nil
+}
+
+// SetSPOEAgentRegisterVars sets the names of variables an agent registers, empty
+// varNames removes the directive. One of version or transactionID is mandatory.
+// Returns error on fail or if a name is not valid, nil on success.
+func (c *SingleSpoe) SetSPOEAgentRegisterVars(scope, agentName string, varNames []string, transactionID string, version int64) error {
+	for _, name := range varNames {
+		if !varNameRegexp.MatchString(name) {
+			return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("invalid variable name %s, must match %s", name, varNameRegexp.String()))
+		}
+	}
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		if len(varNames) == 0 {
+			return p.Set(scope, parser.SPOEAgent, agentName, "register-var-names", nil)
+		}
+		return p.Set(scope, parser.SPOEAgent, agentName, "register-var-names", &types.StringC{Value: strings.Join(varNames, " ")})
+	})
+}
+
+// limits of max-frame-size accepted by HAProxy
+const (
+	MinFrameSize int64 = 256
+	MaxFrameSize int64 = 16384
+)
+
+// GetSPOEAgentMaxFrameSize returns the maximum frame size of an agent, 0 if not set.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentMaxFrameSize(scope, agentName string, transactionID string) (int64, error) {
+	data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, "max-frame-size", transactionID)
+	if err != nil {
+		return 0, err
+	}
+	if d, ok := data.(*types.Int64C); ok {
+		return d.Value, nil
+	}
+	return 0, nil
+}
+
+// SetSPOEAgentMaxFrameSize sets the maximum frame size of an agent, size must be
+// between MinFrameSize and MaxFrameSize. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEAgentMaxFrameSize(scope, agentName string, size int64, transactionID string, version int64) error {
+	if size < MinFrameSize || size > MaxFrameSize {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("max-frame-size %d out of range, must be between %d and %d", size, MinFrameSize, MaxFrameSize))
+	}
+
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		return p.Set(scope, parser.SPOEAgent, agentName, "max-frame-size", &types.Int64C{Value: size})
+	})
+}
+
+// agentFlagOptions are the boolean options of an agent with their default value
+var agentFlagOptions = map[string]bool{ //nolint:gochecknoglobals
+	"async":             true,
+	"continue-on-error": false,
+	"dontlog-normal":    false,
+	"force-set-var":     false,
+	"pipelining":        true,
+	"send-frag-payload": true,
+}
+
+// GetSPOEAgentOption returns whether a boolean option of an agent is enabled,
+// the HAProxy default is returned if option is not set.
+// Returns error on fail or if agent or option does not exist.
+func (c *SingleSpoe) GetSPOEAgentOption(scope, agentName, optionName string, transactionID string) (bool, error) {
+	def, ok := agentFlagOptions[optionName]
+	if !ok {
+		return false, conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown option %s", optionName))
+	}
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return false, err
+	}
+	if !c.checkSectionExists(scope, parser.SPOEAgent, agentName, p) {
+		return false, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", parser.SPOEAgent, agentName))
+	}
+	data, err := p.Get(scope, parser.SPOEAgent, agentName, "option "+optionName, false)
+	if err != nil {
+		return def, nil
+	}
+	if d, ok := data.(*types.SimpleOption); ok {
+		return !d.NoOption, nil
+	}
+	return def, nil
+}
+
+// EnableSPOEAgentOption sets option optionName in an agent.
+// One of version or transactionID is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) EnableSPOEAgentOption(scope, agentName, optionName string, transactionID string, version int64) error {
+	return c.setAgentOption(scope, agentName, optionName, true, transactionID, version)
+}
+
+// DisableSPOEAgentOption sets no option optionName in an agent.
+// One of version or transactionID is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) DisableSPOEAgentOption(scope, agentName, optionName string, transactionID string, version int64) error {
+	return c.setAgentOption(scope, agentName, optionName, false, transactionID, version)
+}
+
+func (c *SingleSpoe) setAgentOption(scope, agentName, optionName string, enabled bool, transactionID string, version int64) error {
+	if _, ok := agentFlagOptions[optionName]; !ok {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown option %s", optionName))
+	}
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		return p.Set(scope, parser.SPOEAgent, agentName, "option "+optionName, &types.SimpleOption{NoOption: !enabled})
+	})
+}
+
+// agentStringOptions are agent options holding a value
+var agentStringOptions = []string{"set-on-error", "set-process-time", "set-total-time", "var-prefix"} //nolint:gochecknoglobals
+
+// SetMultipleSPOEAgentOptions sets several options of an agent, keyed by option name
+// without the option keyword, and saves once. Boolean options take a bool, options
+// holding a value take a string, an empty string removes them. All options are
+// validated before any is set. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetMultipleSPOEAgentOptions(scope, agentName string, options map[string]interface{}, transactionID string, version int64) error {
+	names := make([]string, 0, len(options))
+	for name, value := range options {
+		switch v := value.(type) {
+		case bool:
+			if _, ok := agentFlagOptions[name]; !ok {
+				return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown option %s", name))
+			}
+		case string:
+			if !misc.StringInSlice(name, agentStringOptions) {
+				return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown option %s", name))
+			}
+			if name == "var-prefix" && v != "" && !varPrefixRegexp.MatchString(v) {
+				return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("invalid var-prefix %s, must match %s", v, varPrefixRegexp.String()))
+			}
+		default:
+			return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("invalid value %v of option %s", value, name))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		for _, name := range names {
+			var data common.ParserData
+			switch v := options[name].(type) {
+			case bool:
+				data = &types.SimpleOption{NoOption: !v}
+			case string:
+				if v != "" {
+					data = &types.StringC{Value: v}
+				}
+			}
+			if err := p.Set(scope, parser.SPOEAgent, agentName, "option "+name, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSPOEAgentSendFrag returns whether an agent sends fragmented payloads.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentSendFrag(scope, agentName string, transactionID string) (bool, error) {
+	return c.GetSPOEAgentOption(scope, agentName, "send-frag-payload", transactionID)
+}
+
+// SetSPOEAgentSendFrag enables or disables sending fragmented payloads, the
+// fragmentation capability follows the option. One of version or transactionID
+// is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEAgentSendFrag(scope, agentName string, enabled bool, transactionID string, version int64) error {
+	return c.setAgentOption(scope, agentName, "send-frag-payload", enabled, transactionID, version)
+}
+
+// GetSPOEAgentByIndex returns the agent at index of agents in scope sorted by name.
+// Returns error on fail or if index is out of range.
+func (c *SingleSpoe) GetSPOEAgentByIndex(scope string, index int, transactionID string) (*models.SpoeAgent, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := p.SectionsGet(scope, parser.SPOEAgent)
+	if err != nil {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("scope %s does not exist", scope))
+	}
+	if index < 0 || index >= len(agents) {
+		return nil, conf.NewConfError(conf.ErrObjectIndexOutOfRange, fmt.Sprintf("%s with index %d in scope %s out of range", parser.SPOEAgent, index, scope))
+	}
+	sort.Strings(agents)
+	_, agent, err := c.GetAgent(scope, agents[index], transactionID)
+	return agent, err
+}
+
+// GetFirstSPOEAgent returns the first agent of scope in the order agents are written
+// to the configuration file. Returns error on fail or if there are no agents in scope.
+func (c *SingleSpoe) GetFirstSPOEAgent(scope string, transactionID string) (*models.SpoeAgent, error) {
+	agent, err := c.GetSPOEAgentByIndex(scope, 0, transactionID)
+	var e *conf.ConfError
+	if errors.As(err, &e) && e.Code() == conf.ErrObjectIndexOutOfRange {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("no %s in scope %s", parser.SPOEAgent, scope))
+	}
+	return agent, err
+}
+
+// GetSPOEAgentCount returns the number of agents in scope.
+// Returns error on fail or if scope does not exist.
+func (c *SingleSpoe) GetSPOEAgentCount(scope string, transactionID string) (int, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return 0, err
+	}
+	agents, err := p.SectionsGet(scope, parser.SPOEAgent)
+	if err != nil {
+		return 0, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("scope %s does not exist", scope))
+	}
+	return len(agents), nil
+}
+
+// GetSPOEAgentLastModified returns the modification time of the file the agent is
+// stored in. When the scope of the agent is changed in transactions in progress, the
+// latest modification time of their transaction files is returned, otherwise the one of
+// the configuration file. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentLastModified(scope, agentName string) (time.Time, error) {
+	master, err := c.GetParser("")
+	if err != nil {
+		return time.Time{}, err
+	}
+	inMaster := c.checkSectionExists(scope, parser.SPOEAgent, agentName, master)
+
+	ids := []string{}
+	err = c.ForEachTransaction(func(id string, p *spoe.Parser) error {
+		if !c.checkSectionExists(scope, parser.SPOEAgent, agentName, p) {
+			return nil
+		}
+		if !inMaster || scopeText(master, scope) != scopeText(p, scope) {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !inMaster && len(ids) == 0 {
+		return time.Time{}, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", parser.SPOEAgent, agentName))
+	}
+	if len(ids) == 0 {
+		ids = append(ids, "")
+	}
+
+	var modified time.Time
+	for _, id := range ids {
+		file, err := c.Transaction.GetTransactionFile(id)
+		if err != nil {
+			return time.Time{}, err
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, conf.NewConfError(conf.ErrCannotReadConfFile, err.Error())
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return modified, nil
+}
@@ -0,0 +1,905 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_SetSPOEAgentCapabilities(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		caps      []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "Should disable capabilities which are not listed",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			caps:      []string{"pipelining", "fragmentation"},
+			want:      []string{"pipelining", "fragmentation"},
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on unknown capability",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			caps:      []string{"pipelining", "compression"},
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			caps:      []string{"async"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentCapabilities() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEAgentCapabilities(tt.scope, tt.agentName, tt.caps, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentCapabilities() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentCapabilities(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentCapabilities() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentUseBackend(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		backendName string
+		wantErr     bool
+	}{
+		{
+			name:        "Should change backend",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			backendName: "spoe-agents",
+			wantErr:     false,
+		},
+		{
+			name:        "Should remove backend",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			backendName: "",
+			wantErr:     false,
+		},
+		{
+			name:        "Should fail on unknown agent",
+			scope:       "[ip-reputation]",
+			agentName:   "unknown-agent",
+			backendName: "spoe-agents",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentUseBackend() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEAgentUseBackend(tt.scope, tt.agentName, tt.backendName, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentUseBackend() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentUseBackend(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentUseBackend() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.backendName, got)
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentMaxFrameSize(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		size      int64
+		wantErr   bool
+	}{
+		{
+			name:      "Should set max frame size",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			size:      4096,
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on too small size",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			size:      128,
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on too big size",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			size:      32768,
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			size:      4096,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentMaxFrameSize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEAgentMaxFrameSize(tt.scope, tt.agentName, tt.size, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentMaxFrameSize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentMaxFrameSize(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentMaxFrameSize() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.size, got)
+		})
+	}
+}
+
+func TestSingleSpoe_EnableDisableSPOEAgentOption(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name       string
+		scope      string
+		agentName  string
+		optionName string
+		enable     bool
+		wantErr    bool
+	}{
+		{
+			name:       "Should enable option",
+			scope:      "[ip-reputation]",
+			agentName:  "iprep-agent",
+			optionName: "continue-on-error",
+			enable:     true,
+			wantErr:    false,
+		},
+		{
+			name:       "Should disable option",
+			scope:      "[ip-reputation]",
+			agentName:  "iprep-agent",
+			optionName: "async",
+			enable:     false,
+			wantErr:    false,
+		},
+		{
+			name:       "Should fail on unknown option",
+			scope:      "[ip-reputation]",
+			agentName:  "iprep-agent",
+			optionName: "var-prefix",
+			enable:     true,
+			wantErr:    true,
+		},
+		{
+			name:       "Should fail on unknown agent",
+			scope:      "[ip-reputation]",
+			agentName:  "unknown-agent",
+			optionName: "async",
+			enable:     true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.EnableSPOEAgentOption() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			got, err := ss.GetSPOEAgentOption(tt.scope, tt.agentName, tt.optionName, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.GetSPOEAgentOption() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got == tt.enable {
+				t.Errorf("SingleSpoe.GetSPOEAgentOption() got = %v before change", got)
+			}
+			v, _ := ss.GetVersion("")
+			if tt.enable {
+				err = ss.EnableSPOEAgentOption(tt.scope, tt.agentName, tt.optionName, "", v)
+			} else {
+				err = ss.DisableSPOEAgentOption(tt.scope, tt.agentName, tt.optionName, "", v)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.EnableSPOEAgentOption() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err = ss.GetSPOEAgentOption(tt.scope, tt.agentName, tt.optionName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentOption() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.enable, got)
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentSendFrag(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		enabled   bool
+		wantErr   bool
+	}{
+		{
+			name:      "Should disable fragmentation",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			enabled:   false,
+			wantErr:   false,
+		},
+		{
+			name:      "Should enable fragmentation",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			enabled:   true,
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			enabled:   true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentSendFrag() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEAgentSendFrag(tt.scope, tt.agentName, tt.enabled, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentSendFrag() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentSendFrag(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentSendFrag() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.enabled, got)
+			caps, _ := ss.GetSPOEAgentCapabilities(tt.scope, tt.agentName, "")
+			assert.Equal(t, tt.enabled, misc.StringInSlice(CapabilityFragmentation, caps))
+		})
+	}
+}
+
+func TestSingleSpoe_GetSPOEAgentByIndex(t *testing.T) {
+	config := basicConfig + `
+spoe-agent another-agent
+    messages check-client-ip
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		scope   string
+		index   int
+		want    string
+		wantErr bool
+	}{
+		{name: "Should return first agent", scope: "[ip-reputation]", index: 0, want: "another-agent", wantErr: false},
+		{name: "Should return second agent", scope: "[ip-reputation]", index: 1, want: "iprep-agent", wantErr: false},
+		{name: "Should fail on index out of range", scope: "[ip-reputation]", index: 2, wantErr: true},
+		{name: "Should fail on negative index", scope: "[ip-reputation]", index: -1, wantErr: true},
+		{name: "Should fail on unknown scope", scope: "[unknown]", index: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ss.GetSPOEAgentByIndex(tt.scope, tt.index, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.GetSPOEAgentByIndex() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, *got.Name)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_GetFirstSPOEAgent(t *testing.T) {
+	config := basicConfig + `
+[empty]
+spoe-message first
+    args src
+    event on-client-session
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.GetFirstSPOEAgent("[ip-reputation]", "")
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetFirstSPOEAgent() error = %v", err)
+	}
+	assert.Equal(t, "iprep-agent", *got.Name)
+
+	_, err = ss.GetFirstSPOEAgent("[empty]", "")
+	var e *conf.ConfError
+	if !errors.As(err, &e) || e.Code() != conf.ErrObjectDoesNotExist {
+		t.Errorf("SingleSpoe.GetFirstSPOEAgent() error = %v, want ErrObjectDoesNotExist", err)
+	}
+}
+
+func TestSingleSpoe_GetSPOECounts(t *testing.T) {
+	config := basicConfig + `
+spoe-agent another-agent
+    groups mygroup
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.GetSPOEAgentCount("[ip-reputation]", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentCount() error = %v", err)
+	}
+	assert.Equal(t, 2, got)
+	if _, err = ss.GetSPOEAgentCount("[unknown]", ""); err == nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentCount() error = nil, want error")
+	}
+
+	got, err = ss.GetSPOEMessageCount("[ip-reputation]", "iprep-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEMessageCount() error = %v", err)
+	}
+	assert.Equal(t, 1, got)
+	got, err = ss.GetSPOEMessageCount("[ip-reputation]", "another-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEMessageCount() error = %v", err)
+	}
+	assert.Equal(t, 1, got)
+	if _, err = ss.GetSPOEMessageCount("[ip-reputation]", "unknown-agent", ""); err == nil {
+		t.Errorf("SingleSpoe.GetSPOEMessageCount() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentVarPrefix(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		prefix    string
+		wantErr   bool
+	}{
+		{
+			name:      "Should change prefix",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			prefix:    "spoe_iprep2",
+			wantErr:   false,
+		},
+		{
+			name:      "Should remove prefix",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			prefix:    "",
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on uppercase prefix",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			prefix:    "IPrep",
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on leading digit",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			prefix:    "1iprep",
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			prefix:    "iprep",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentVarPrefix() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEAgentVarPrefix(tt.scope, tt.agentName, tt.prefix, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentVarPrefix() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentVarPrefix(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentVarPrefix() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.prefix, got)
+		})
+	}
+}
+
+func TestSingleSpoe_GetSPOEAgentLastModified(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	scope := "[ip-reputation]"
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err = os.Chtimes(params.ConfigurationFile, past, past); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+
+	got, err := ss.GetSPOEAgentLastModified(scope, "iprep-agent")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentLastModified() error = %v", err)
+	}
+	if !got.Equal(past) {
+		t.Errorf("SingleSpoe.GetSPOEAgentLastModified() got = %v, want %v", got, past)
+	}
+
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	got, _ = ss.GetSPOEAgentLastModified(scope, "iprep-agent")
+	if !got.Equal(past) {
+		t.Errorf("SingleSpoe.GetSPOEAgentLastModified() got = %v, want %v", got, past)
+	}
+	if _, err = ss.SetSPOEAgentIdleTimeout(scope, "iprep-agent", time.Minute, tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentIdleTimeout() error = %v", err)
+	}
+	got, _ = ss.GetSPOEAgentLastModified(scope, "iprep-agent")
+	if !got.After(past) {
+		t.Errorf("SingleSpoe.GetSPOEAgentLastModified() got = %v, want after %v", got, past)
+	}
+
+	if _, err = ss.GetSPOEAgentLastModified(scope, "unknown-agent"); err == nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentLastModified() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_SetMultipleSPOEAgentOptions(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	scope, agent := "[ip-reputation]", "iprep-agent"
+	tests := []struct {
+		name        string
+		options     map[string]interface{}
+		wantVersion int64
+		wantErr     bool
+	}{
+		{
+			name: "Should set all options with one save",
+			options: map[string]interface{}{
+				"async":          false,
+				"dontlog-normal": true,
+				"var-prefix":     "newprefix",
+				"set-on-error":   "error",
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "Should fail on unknown option and set nothing",
+			options: map[string]interface{}{
+				"async":          true,
+				"unknown-option": true,
+			},
+			wantVersion: 2,
+			wantErr:     true,
+		},
+		{
+			name: "Should fail on invalid value type",
+			options: map[string]interface{}{
+				"async": "yes",
+			},
+			wantVersion: 2,
+			wantErr:     true,
+		},
+		{
+			name: "Should fail on invalid var-prefix",
+			options: map[string]interface{}{
+				"var-prefix": "Invalid-Prefix",
+			},
+			wantVersion: 2,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetMultipleSPOEAgentOptions(scope, agent, tt.options, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetMultipleSPOEAgentOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if v, _ = ss.GetVersion(""); v != tt.wantVersion {
+				t.Errorf("SingleSpoe.GetVersion() got = %v, want %v", v, tt.wantVersion)
+			}
+			async, _ := ss.GetSPOEAgentOption(scope, agent, "async", "")
+			assert.False(t, async)
+			dontlog, _ := ss.GetSPOEAgentOption(scope, agent, "dontlog-normal", "")
+			assert.True(t, dontlog)
+			prefix, _ := ss.GetSPOEAgentVarPrefix(scope, agent, "")
+			assert.Equal(t, "newprefix", prefix)
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentRegisterVars(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		varNames  []string
+		wantErr   bool
+	}{
+		{
+			name:      "Should set variable names",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			varNames:  []string{"score", "ip.reputation", "Last_Seen"},
+			wantErr:   false,
+		},
+		{
+			name:      "Should remove variable names",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			varNames:  []string{},
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on invalid character",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			varNames:  []string{"score", "ip-reputation"},
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on empty name",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			varNames:  []string{""},
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			varNames:  []string{"score"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentRegisterVars() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEAgentRegisterVars(tt.scope, tt.agentName, tt.varNames, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentRegisterVars() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentRegisterVars(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentRegisterVars() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.varNames, got)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/types"
+)
+
+// annotationPrefix starts comments holding metadata about the configuration
+const annotationPrefix = "_annotation_"
+
+// ClearAllAnnotations removes all # _annotation_* comments from the transaction, other
+// comments, sections and the version are kept. Returns error on fail, nil on success.
+func (c *SingleSpoe) ClearAllAnnotations(id string) error {
+	p, t, err := c.loadDataForChange(id, 0)
+	if err != nil {
+		return err
+	}
+
+	for scope := range p.Parsers {
+		data, err := p.Get(scope, parser.Comments, parser.CommentsSectionName, "#")
+		if err != nil {
+			continue
+		}
+		comments, ok := data.([]types.Comments)
+		if !ok {
+			continue
+		}
+		kept := []types.Comments{}
+		for _, comment := range comments {
+			if !strings.HasPrefix(comment.Value, annotationPrefix) {
+				kept = append(kept, comment)
+			}
+		}
+		if len(kept) == len(comments) {
+			continue
+		}
+		var value interface{} = kept
+		if len(kept) == 0 {
+			value = nil
+		}
+		if err := p.Set(scope, parser.Comments, parser.CommentsSectionName, "#", value); err != nil {
+			return c.Transaction.HandleError(scope, "", "", t, false, err)
+		}
+	}
+
+	return c.Transaction.SaveData(p, t, false)
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_ClearAllAnnotations(t *testing.T) {
+	config := `# _version=1
+# _annotation_owner=other-system
+# keep this comment
+# _annotation_imported=2020-01-01
+` + basicConfig[len("# _version=1\n"):]
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	if err = ss.ClearAllAnnotations(tr.ID); err != nil {
+		t.Fatalf("SingleSpoe.ClearAllAnnotations() error = %v", err)
+	}
+
+	tFile, err := ss.Transaction.GetTransactionFile(tr.ID)
+	if err != nil {
+		t.Fatalf("Transaction.GetTransactionFile() error = %v", err)
+	}
+	content, err := ioutil.ReadFile(tFile)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	want := &spoe.Parser{}
+	if err = want.ParseData("# _version=1\n# keep this comment\n" + basicConfig[len("# _version=1\n"):]); err != nil {
+		t.Fatalf("spoe.Parser.ParseData() error = %v", err)
+	}
+	assert.Equal(t, want.String(), string(content))
+
+	if err = ss.ClearAllAnnotations(""); err == nil {
+		t.Errorf("SingleSpoe.ClearAllAnnotations() error = nil, want error")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+// errNotModified stops syncConfigurationFile when the file matches the master parser
+var errNotModified = errors.New("not modified") //nolint:gochecknoglobals
+
+// autoSyncInterval is how often StartAutoSync checks the configuration file
+var autoSyncInterval = time.Second //nolint:gochecknoglobals
+
+// StartAutoSync watches the configuration file until ctx is done or Close is called. When the file is
+// modified externally the master parser is reloaded from it with the version
+// incremented, and onConflict, if not nil, is called for every transaction in
+// progress changing a scope the external modification also changed.
+// Returns error if the configuration file can not be read.
+func (c *SingleSpoe) StartAutoSync(ctx context.Context, onConflict func(err error)) error {
+	fi, err := os.Stat(c.Transaction.ConfigurationFile)
+	if err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s: %s", c.Transaction.ConfigurationFile, err.Error()))
+	}
+
+	ticker := time.NewTicker(autoSyncInterval)
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		defer ticker.Stop()
+		modTime := fi.ModTime()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closing():
+				return
+			case <-ticker.C:
+				fi, err := os.Stat(c.Transaction.ConfigurationFile)
+				if err != nil || fi.ModTime().Equal(modTime) {
+					continue
+				}
+				modTime = fi.ModTime()
+				if !c.syncConfigurationFile(onConflict) {
+					continue
+				}
+				if fi, err = os.Stat(c.Transaction.ConfigurationFile); err == nil {
+					modTime = fi.ModTime()
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// syncConfigurationFile reloads the master parser when the configuration file differs
+// from it, returns true if the master parser was replaced. The file is read, saved
+// with the version incremented and the master parser replaced holding the commit
+// lock, so a commit can not write the file in between.
+func (c *SingleSpoe) syncConfigurationFile(onConflict func(err error)) bool {
+	var old, p *spoe.Parser
+	err := c.Transaction.WithCommitLock(func() error {
+		data, err := readConfigFile(c.Transaction.ConfigurationFile)
+		if err != nil {
+			return err
+		}
+		master, err := c.GetParser("")
+		if err != nil {
+			return err
+		}
+		// written by this client
+		if strings.TrimSpace(data) == strings.TrimSpace(master.String()) {
+			return errNotModified
+		}
+
+		p = &spoe.Parser{}
+		if err = p.ParseData(data); err != nil {
+			return err
+		}
+		v, err := parserVersion(master)
+		if err != nil {
+			return err
+		}
+		ver, err := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
+		if err != nil {
+			return err
+		}
+		if d, ok := ver.(*types.ConfigVersion); ok {
+			d.Value = v + 1
+		}
+		if err = p.Save(c.Transaction.ConfigurationFile); err != nil {
+			return err
+		}
+		old = c.setParser(p)
+		return nil
+	})
+	if err != nil {
+		return false
+	}
+	c.notifyVersionChange()
+
+	if onConflict == nil {
+		return true
+	}
+	external := changedScopes(old, p)
+	for _, t := range c.GetParserTransactions() {
+		tp, err := c.GetParser(t.ID)
+		if err != nil {
+			continue
+		}
+		conflicts := []string{}
+		for _, scope := range changedScopes(old, tp) {
+			if misc.StringInSlice(scope, external) {
+				conflicts = append(conflicts, scope)
+			}
+		}
+		if len(conflicts) > 0 {
+			onConflict(conf.NewConfError(conf.ErrVersionMismatch, fmt.Sprintf("transaction %s changes scopes %s modified externally in %s", t.ID, strings.Join(conflicts, ", "), c.Transaction.ConfigurationFile)))
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_StartAutoSync(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	if err = ss.SetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "spoe-agents", tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+	}
+
+	interval := autoSyncInterval
+	autoSyncInterval = 10 * time.Millisecond
+	defer func() { autoSyncInterval = interval }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conflicts := make(chan error, 1)
+	if err = ss.StartAutoSync(ctx, func(err error) { conflicts <- err }); err != nil {
+		t.Fatalf("SingleSpoe.StartAutoSync() error = %v", err)
+	}
+	versions, err := ss.SubscribeVersionChanges(ctx)
+	if err != nil {
+		t.Fatalf("SingleSpoe.SubscribeVersionChanges() error = %v", err)
+	}
+
+	// modification time resolution of some filesystems is coarse
+	time.Sleep(20 * time.Millisecond)
+	external := strings.Replace(basicConfig, "use-backend agents", "use-backend other-agents", 1)
+	if err = ioutil.WriteFile(params.ConfigurationFile, []byte(external), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+
+	select {
+	case v := <-versions:
+		if v != 2 {
+			t.Errorf("SingleSpoe.StartAutoSync() version got = %v, want 2", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SingleSpoe.StartAutoSync() configuration not reloaded")
+	}
+	select {
+	case err := <-conflicts:
+		if !strings.Contains(err.Error(), tr.ID) {
+			t.Errorf("SingleSpoe.StartAutoSync() conflict = %v, want transaction %s", err, tr.ID)
+		}
+	case <-time.After(time.Second):
+		t.Error("SingleSpoe.StartAutoSync() no conflict reported")
+	}
+
+	got, err := ss.GetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentUseBackend() error = %v", err)
+	}
+	if got != "other-agents" {
+		t.Errorf("SingleSpoe.GetSPOEAgentUseBackend() got = %v, want other-agents", got)
+	}
+}
+
+func TestSingleSpoe_syncConfigurationFileDuringCommit(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	configPath := filepath.Join(dir, configFile)
+	ss, err := newSingleSpoe(Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: configPath,
+	})
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			external := strings.Replace(basicConfig, "use-backend agents", fmt.Sprintf("use-backend agents-%d", i), 1)
+			if err := ioutil.WriteFile(configPath, []byte(external), 0600); err != nil {
+				t.Errorf("ioutil.WriteFile() error = %v", err)
+				return
+			}
+			ss.syncConfigurationFile(nil)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			v, _ := ss.GetVersion("")
+			tr, err := ss.Transaction.StartTransaction(v)
+			if err != nil {
+				// synced after the version was read
+				continue
+			}
+			_, _ = ss.Transaction.CommitTransaction(tr.ID)
+		}
+	}()
+	wg.Wait()
+	ss.syncConfigurationFile(nil)
+
+	v, err := ss.GetVersion("")
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetVersion() error = %v", err)
+	}
+	fileVersion, err := ReadSPOEFileVersion(configPath)
+	if err != nil {
+		t.Fatalf("ReadSPOEFileVersion() error = %v", err)
+	}
+	if fileVersion != v {
+		t.Errorf("ReadSPOEFileVersion() got = %v, want %v", fileVersion, v)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/google/renameio"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// gzipMagic are the first bytes of a gzip compressed file
+var gzipMagic = []byte{0x1f, 0x8b} //nolint:gochecknoglobals
+
+// CompressBackups gzip compresses backup files of the configuration file in place,
+// keeping their names. Backups already compressed are skipped.
+// Returns number of files compressed and error on fail.
+func (c *SingleSpoe) CompressBackups() (int, error) {
+	backups, err := c.backupFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, f := range backups {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return count, conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", f))
+		}
+		if bytes.HasPrefix(data, gzipMagic) {
+			continue
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err = zw.Write(data); err != nil {
+			return count, err
+		}
+		if err = zw.Close(); err != nil {
+			return count, err
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return count, err
+		}
+		if err = renameio.WriteFile(f, buf.Bytes(), info.Mode()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// backupFiles returns backup files of the configuration file, named
+// <configuration file>.<version>
+func (c *SingleSpoe) backupFiles() ([]string, error) {
+	configFile := c.Transaction.ConfigurationFile
+	files, err := ioutil.ReadDir(filepath.Dir(configFile))
+	if err != nil {
+		return nil, err
+	}
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(filepath.Base(configFile)) + `\.\d+$`)
+	backups := []string{}
+	for _, f := range files {
+		if f.IsDir() || !re.MatchString(f.Name()) {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(configFile), f.Name()))
+	}
+	return backups, nil
+}
+
+// readConfigFile returns the content of a configuration file, gzip compressed
+// files are decompressed
+func readConfigFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return string(data), nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	data, err = ioutil.ReadAll(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_CompressBackups(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	configPath := filepath.Join(dir, configFile)
+	backupFile := fmt.Sprintf("%s.1", configPath)
+	defer func() {
+		_ = remove(backupFile)
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: configPath,
+		BackupsNumber:     2,
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	v, _ := ss.GetVersion("")
+	if err = ss.SetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "spoe-agents", "", v); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+	}
+
+	got, err := ss.CompressBackups()
+	if err != nil || got != 1 {
+		t.Errorf("SingleSpoe.CompressBackups() = %v, %v, want 1", got, err)
+	}
+	data, _ := ioutil.ReadFile(backupFile)
+	if !bytes.HasPrefix(data, gzipMagic) {
+		t.Errorf("SingleSpoe.CompressBackups() %s is not compressed", backupFile)
+	}
+	got, err = ss.CompressBackups()
+	if err != nil || got != 0 {
+		t.Errorf("SingleSpoe.CompressBackups() = %v, %v, want 0", got, err)
+	}
+
+	if err = ss.LoadData(backupFile); err != nil {
+		t.Errorf("SingleSpoe.LoadData() error = %v", err)
+		return
+	}
+	backend, _ := ss.GetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "")
+	if backend != "agents" {
+		t.Errorf("SingleSpoe.LoadData() use-backend = %v, want agents", backend)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultDrainTimeout is how long Close waits for background goroutines when
+// Params.DrainTimeout is not set
+const DefaultDrainTimeout = 5 * time.Second
+
+// ErrDrainTimeout is returned by Close when background goroutines did not stop
+// within the drain timeout
+var ErrDrainTimeout = errors.New("background goroutines did not stop within drain timeout") //nolint:gochecknoglobals
+
+// Close stops the transaction reaper and goroutines started by StartAutoSync,
+// SubscribeVersionChanges and ListenForTransactionCommits, closing their
+// channels, and waits for the goroutines to finish for at most the drain
+// timeout. The configuration and all loaded transactions are then written with
+// SaveAll. Returns the SaveAll error on fail,
+// ErrDrainTimeout if goroutines did not finish in time, nil on success.
+func (c *SingleSpoe) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.StopReaper()
+
+	drained := make(chan struct{})
+	go func() {
+		c.workers.Wait()
+		close(drained)
+	}()
+	timeout := c.drainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var drainErr error
+	select {
+	case <-drained:
+	case <-timer.C:
+		drainErr = ErrDrainTimeout
+	}
+
+	if err := c.SaveAll(); err != nil {
+		return err
+	}
+	return drainErr
+}
+
+// closing returns a channel closed when Close is called, nil for clients not
+// created with newSingleSpoe
+func (c *SingleSpoe) closing() <-chan struct{} {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Done()
+}
+
+// newCloseContext sets up the context canceled by Close
+func (c *SingleSpoe) newCloseContext(drainTimeout time.Duration) {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.drainTimeout = drainTimeout
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_Close(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+		DrainTimeout:      time.Second,
+	}
+
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := ss.StartAutoSync(ctx, nil); err != nil {
+		t.Fatalf("SingleSpoe.StartAutoSync() error = %v", err)
+	}
+	versions, err := ss.SubscribeVersionChanges(ctx)
+	if err != nil {
+		t.Fatalf("SingleSpoe.SubscribeVersionChanges() error = %v", err)
+	}
+	commits := ss.ListenForTransactionCommits(ctx, nil)
+
+	if err := ss.Close(); err != nil {
+		t.Errorf("SingleSpoe.Close() error = %v", err)
+	}
+	if _, ok := <-versions; ok {
+		t.Errorf("SingleSpoe.SubscribeVersionChanges() channel not closed")
+	}
+	if _, ok := <-commits; ok {
+		t.Errorf("SingleSpoe.ListenForTransactionCommits() channel not closed")
+	}
+
+	// a goroutine which does not stop times out
+	ss, err = newSingleSpoe(Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+		DrainTimeout:      10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	ss.workers.Add(1)
+	defer ss.workers.Done()
+	if err := ss.Close(); !errors.Is(err, ErrDrainTimeout) {
+		t.Errorf("SingleSpoe.Close() error = %v, want %v", err, ErrDrainTimeout)
+	}
+}
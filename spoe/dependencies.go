@@ -0,0 +1,81 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"sort"
+
+	parser "github.com/haproxytech/config-parser/v3"
+)
+
+// SectionRef identifies a section of a scope
+type SectionRef struct {
+	Section parser.Section
+	Name    string
+}
+
+// DependencyEdge is a reference from one section to another
+type DependencyEdge struct {
+	From SectionRef
+	To   SectionRef
+}
+
+// DependencyGraph holds the sections of a scope and the references between them
+type DependencyGraph struct {
+	Nodes []SectionRef
+	Edges []DependencyEdge
+}
+
+// SectionDependencyMap returns the graph of references between sections of scope, with
+// an edge from an agent to each of its groups and messages, and from a group to each
+// of its messages. References to sections which do not exist are left out. Nodes and
+// edges are ordered by section type and name. Returns error on fail.
+func (c *SingleSpoe) SectionDependencyMap(scope string, transactionID string) (*DependencyGraph, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &DependencyGraph{Nodes: []SectionRef{}, Edges: []DependencyEdge{}}
+	for _, section := range sectionTypes {
+		names, err := p.SectionsGet(scope, section)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			graph.Nodes = append(graph.Nodes, SectionRef{Section: section, Name: name})
+		}
+	}
+
+	addEdges := func(from SectionRef, section parser.Section, names []string) {
+		for _, name := range names {
+			if c.checkSectionExists(scope, section, name, p) {
+				graph.Edges = append(graph.Edges, DependencyEdge{From: from, To: SectionRef{Section: section, Name: name}})
+			}
+		}
+	}
+	for _, node := range graph.Nodes {
+		switch node.Section { //nolint:exhaustive
+		case parser.SPOEAgent:
+			addEdges(node, parser.SPOEGroup, sectionList(scope, parser.SPOEAgent, node.Name, "groups", p))
+			addEdges(node, parser.SPOEMessage, sectionList(scope, parser.SPOEAgent, node.Name, "messages", p))
+		case parser.SPOEGroup:
+			addEdges(node, parser.SPOEMessage, groupMessages(scope, node.Name, p))
+		}
+	}
+	return graph, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_SectionDependencyMap(t *testing.T) {
+	config := basicConfig + `
+spoe-agent group-agent
+    groups mygroup unknown-group
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.SectionDependencyMap("[ip-reputation]", "")
+	if err != nil {
+		t.Fatalf("SingleSpoe.SectionDependencyMap() error = %v", err)
+	}
+	groupAgent := SectionRef{Section: parser.SPOEAgent, Name: "group-agent"}
+	iprepAgent := SectionRef{Section: parser.SPOEAgent, Name: "iprep-agent"}
+	group := SectionRef{Section: parser.SPOEGroup, Name: "mygroup"}
+	message := SectionRef{Section: parser.SPOEMessage, Name: "check-client-ip"}
+	want := &DependencyGraph{
+		Nodes: []SectionRef{groupAgent, iprepAgent, group, message},
+		Edges: []DependencyEdge{
+			{From: groupAgent, To: group},
+			{From: iprepAgent, To: message},
+		},
+	}
+	assert.Equal(t, want, got)
+
+	if _, err = ss.SectionDependencyMap("[unknown]", ""); err == nil {
+		t.Errorf("SingleSpoe.SectionDependencyMap() error = nil, want error")
+	}
+}
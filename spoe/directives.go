@@ -0,0 +1,145 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/types"
+)
+
+// sectionTypes are all section types a SPOE scope can hold
+var sectionTypes = []parser.Section{parser.SPOEAgent, parser.SPOEGroup, parser.SPOEMessage} //nolint:gochecknoglobals
+
+// ReplaceAllOccurrences replaces the value of directiveKey in all sections of scope
+// where it equals oldValue with newValue. Only directives holding a single string
+// or number value are replaced. One of version or transactionID is mandatory.
+// Returns number of replacements made, warnings and error on fail. With validation,
+// an unknown directiveKey is reported as a warning, the replacements are still done.
+func (c *SingleSpoe) ReplaceAllOccurrences(scope string, directiveKey, oldValue, newValue string, transactionID string, version int64) (int, []*LintWarning, error) {
+	warnings := []*LintWarning{}
+	if c.Transaction.UseValidation && c.Schema != nil {
+		if err := c.Schema.CheckDirective(directiveKey); err != nil {
+			warnings = append(warnings, &LintWarning{
+				Scope:   scope,
+				Name:    directiveKey,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	count := 0
+	for _, section := range sectionTypes {
+		names, err := p.SectionsGet(scope, section)
+		if err != nil {
+			return 0, nil, c.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+		}
+		for _, name := range names {
+			data, err := p.Get(scope, section, name, directiveKey, false)
+			if err != nil {
+				continue
+			}
+			switch d := data.(type) {
+			case *types.StringC:
+				if d.Value != oldValue {
+					continue
+				}
+				d.Value = newValue
+			case *types.Int64C:
+				if strconv.FormatInt(d.Value, 10) != oldValue {
+					continue
+				}
+				v, err := strconv.ParseInt(newValue, 10, 64)
+				if err != nil {
+					return 0, nil, c.Transaction.HandleError(directiveKey, "", "", t, transactionID == "", err)
+				}
+				d.Value = v
+			default:
+				continue
+			}
+			if err := p.Set(scope, section, name, directiveKey, data); err != nil {
+				return 0, nil, c.Transaction.HandleError(directiveKey, string(section), name, t, transactionID == "", err)
+			}
+			count++
+		}
+	}
+
+	// nothing changed, do not commit an implicit transaction
+	if count == 0 {
+		if transactionID == "" {
+			_ = c.Transaction.DeleteTransaction(t)
+		}
+		return 0, warnings, nil
+	}
+
+	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
+		return 0, nil, err
+	}
+	return count, warnings, nil
+}
+
+// ConfigLineSearch returns sections of scope where the value of any directive
+// contains query, ordered by section type and name. Returns error on fail.
+func (c *SingleSpoe) ConfigLineSearch(scope string, query string, transactionID string) ([]SectionRef, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []SectionRef{}
+	for _, section := range sectionTypes {
+		names, err := p.SectionsGet(scope, section)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sectionContains(p.Parsers[scope][section][name], query) {
+				found = append(found, SectionRef{Section: section, Name: name})
+			}
+		}
+	}
+	return found, nil
+}
+
+// sectionContains reports if the value of any directive of section contains query,
+// directive names are not searched
+func sectionContains(sectionParsers *parser.Parsers, query string) bool {
+	if sectionParsers == nil {
+		return false
+	}
+	for directive, directiveParser := range sectionParsers.Parsers {
+		lines, _, err := directiveParser.ResultAll()
+		if err != nil {
+			continue
+		}
+		for _, line := range lines {
+			value := strings.TrimSpace(strings.TrimPrefix(line.Data, directive))
+			if strings.Contains(value, query) {
+				return true
+			}
+		}
+	}
+	return false
+}
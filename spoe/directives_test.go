@@ -0,0 +1,184 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v3"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_ReplaceAllOccurrences(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name         string
+		scope        string
+		directiveKey string
+		oldValue     string
+		newValue     string
+		want         int
+		wantVersion  int64
+		wantErr      bool
+	}{
+		{
+			name:         "Should replace backend of agents",
+			scope:        "[ip-reputation]",
+			directiveKey: "use-backend",
+			oldValue:     "agents",
+			newValue:     "spoe-agents",
+			want:         1,
+			wantVersion:  2,
+			wantErr:      false,
+		},
+		{
+			name:         "Should replace nothing and keep version",
+			scope:        "[ip-reputation]",
+			directiveKey: "messages",
+			oldValue:     "unknown-message",
+			newValue:     "other-message",
+			want:         0,
+			wantVersion:  2,
+			wantErr:      false,
+		},
+		{
+			name:         "Should fail on unknown scope",
+			scope:        "[unknown]",
+			directiveKey: "use-backend",
+			oldValue:     "agents",
+			newValue:     "spoe-agents",
+			wantVersion:  2,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.ReplaceAllOccurrences() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			got, _, err := ss.ReplaceAllOccurrences(tt.scope, tt.directiveKey, tt.oldValue, tt.newValue, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.ReplaceAllOccurrences() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SingleSpoe.ReplaceAllOccurrences() got = %v, want %v", got, tt.want)
+			}
+			if v, _ = ss.GetVersion(""); v != tt.wantVersion {
+				t.Errorf("SingleSpoe.GetVersion() got = %v, want %v", v, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_ConfigLineSearch(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name    string
+		scope   string
+		query   string
+		want    []SectionRef
+		wantErr bool
+	}{
+		{
+			name:  "Should find agent by messages it sends",
+			scope: "[ip-reputation]",
+			query: "check-client",
+			want: []SectionRef{
+				{Section: parser.SPOEAgent, Name: "iprep-agent"},
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Should find message by argument",
+			scope: "[ip-reputation]",
+			query: "ip=src",
+			want: []SectionRef{
+				{Section: parser.SPOEMessage, Name: "check-client-ip"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Should not match directive names",
+			scope:   "[ip-reputation]",
+			query:   "use-backend",
+			want:    []SectionRef{},
+			wantErr: false,
+		},
+		{
+			name:    "Should fail on unknown scope",
+			scope:   "[unknown]",
+			query:   "agents",
+			wantErr: true,
+		},
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ss.ConfigLineSearch(tt.scope, tt.query, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.ConfigLineSearch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SingleSpoe.ConfigLineSearch() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
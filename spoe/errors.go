@@ -0,0 +1,42 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// SPOEError is a configuration error with the scope and section type it relates to
+type SPOEError struct {
+	*conf.ConfError
+	Scope       string
+	SectionType string
+}
+
+// Error implementation for SPOEError
+func (e *SPOEError) Error() string {
+	if e.SectionType == "" {
+		return fmt.Sprintf("%s (scope %s)", e.ConfError.Error(), e.Scope)
+	}
+	return fmt.Sprintf("%s (scope %s, %s)", e.ConfError.Error(), e.Scope, e.SectionType)
+}
+
+// Unwrap returns the wrapped ConfError
+func (e *SPOEError) Unwrap() error {
+	return e.ConfError
+}
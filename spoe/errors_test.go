@@ -0,0 +1,64 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_SPOEError(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	v, _ := ss.GetVersion("")
+	err = ss.DeleteAgent("[ip-reputation]", "unknown-agent", "", v)
+
+	spoeErr, ok := err.(*SPOEError)
+	if !ok {
+		t.Fatalf("SingleSpoe.DeleteAgent() error = %v, want *SPOEError", err)
+	}
+	if spoeErr.Scope != "[ip-reputation]" || spoeErr.SectionType != "spoe-agent" || spoeErr.Code() != conf.ErrObjectDoesNotExist {
+		t.Errorf("SingleSpoe.DeleteAgent() error = %+v", spoeErr)
+	}
+	var confErr *conf.ConfError
+	if !errors.As(err, &confErr) || confErr.Code() != conf.ErrObjectDoesNotExist {
+		t.Errorf("SingleSpoe.DeleteAgent() error does not wrap ConfError")
+	}
+}
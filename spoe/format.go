@@ -0,0 +1,157 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	spoe_types "github.com/haproxytech/config-parser/v3/spoe/types"
+	"github.com/haproxytech/config-parser/v3/types"
+)
+
+// prettyIndent is the indentation of directives in PrettyPrint output
+const prettyIndent = "    "
+
+// PrettyPrint returns the configuration serialized with one blank line between
+// sections and directives indented with four spaces. Scopes and sections are
+// ordered alphabetically by the parser.
+// Returns error on fail.
+func (c *SingleSpoe) PrettyPrint(transactionID string) (string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	inSection := false
+	afterScope := false
+	for _, line := range strings.Split(p.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		switch {
+		case isScopeHeader(line):
+			if inSection {
+				b.WriteString("\n")
+			}
+			inSection = false
+			afterScope = true
+		case isSectionHeader(line):
+			if b.Len() > 0 && !afterScope {
+				b.WriteString("\n")
+			}
+			inSection = true
+			afterScope = false
+		case inSection:
+			b.WriteString(prettyIndent)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func isSectionHeader(line string) bool {
+	for _, section := range sectionTypes {
+		if strings.HasPrefix(line, string(section)+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// tree branches used by PrintTree
+const (
+	treeBranch     = "├── "
+	treeLastBranch = "└── "
+	treeIndent     = "│   "
+	treeLastIndent = "    "
+)
+
+// PrintTree writes the agents of each scope to w as an ASCII tree, with the groups
+// and messages each agent uses. Returns error on fail.
+func (c *SingleSpoe) PrintTree(transactionID string, w io.Writer) error {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return err
+	}
+	scopes := []string{}
+	for scope := range p.Parsers {
+		if p.IsScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	var b strings.Builder
+	for _, scope := range scopes {
+		b.WriteString(scope + "\n")
+		agents, _ := p.SectionsGet(scope, parser.SPOEAgent)
+		sort.Strings(agents)
+		for i, agent := range agents {
+			branch, indent := treeBranch, treeIndent
+			if i == len(agents)-1 {
+				branch, indent = treeLastBranch, treeLastIndent
+			}
+			fmt.Fprintf(&b, "%s%s: %s\n", branch, parser.SPOEAgent, agent)
+			children := agentTreeNodes(scope, agent, p)
+			for j, child := range children {
+				if j == len(children)-1 {
+					fmt.Fprintf(&b, "%s%s%s\n", indent, treeLastBranch, child)
+				} else {
+					fmt.Fprintf(&b, "%s%s%s\n", indent, treeBranch, child)
+				}
+			}
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// agentTreeNodes returns the tree nodes of groups and messages an agent uses
+func agentTreeNodes(scope, agent string, p *spoe.Parser) []string {
+	nodes := []string{}
+	for _, directive := range []string{"groups", "messages"} {
+		data, err := p.Get(scope, parser.SPOEAgent, agent, directive, false)
+		if err != nil {
+			continue
+		}
+		d, ok := data.(*types.StringC)
+		if !ok {
+			continue
+		}
+		for _, name := range strings.Fields(d.Value) {
+			if directive == "groups" {
+				nodes = append(nodes, fmt.Sprintf("%s: %s (messages: %s)", parser.SPOEGroup, name, strings.Join(groupMessages(scope, name, p), ", ")))
+				continue
+			}
+			event := "no event"
+			if data, err := p.Get(scope, parser.SPOEMessage, name, "event", false); err == nil {
+				if e, ok := data.(*spoe_types.Event); ok {
+					event = e.Name
+				}
+			}
+			nodes = append(nodes, fmt.Sprintf("%s: %s (%s)", parser.SPOEMessage, name, event))
+		}
+	}
+	return nodes
+}
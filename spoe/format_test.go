@@ -0,0 +1,123 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_PrettyPrint(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.PrettyPrint("")
+	if err != nil {
+		t.Errorf("SingleSpoe.PrettyPrint() error = %v", err)
+		return
+	}
+	want := `# _version=1
+[ip-reputation]
+spoe-agent iprep-agent
+    log global
+    messages check-client-ip
+    option async
+    option var-prefix iprep
+    timeout hello 2s
+    timeout idle 2m
+    timeout processing 10ms
+    use-backend agents
+
+spoe-group mygroup
+    messages mymessage
+
+spoe-message check-client-ip
+    args ip=src
+    event on-client-session if ! { src -f /etc/haproxy/whitelist.lst }
+`
+	assert.Equal(t, want, got)
+}
+
+func TestSingleSpoe_PrintTree(t *testing.T) {
+	config := basicConfig + `
+spoe-agent group-agent
+    groups mygroup
+    messages check-client-ip
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	var b strings.Builder
+	if err = ss.PrintTree("", &b); err != nil {
+		t.Errorf("SingleSpoe.PrintTree() error = %v", err)
+		return
+	}
+	want := `[ip-reputation]
+├── spoe-agent: group-agent
+│   ├── spoe-group: mygroup (messages: mymessage)
+│   └── spoe-message: check-client-ip (on-client-session)
+└── spoe-agent: iprep-agent
+    └── spoe-message: check-client-ip (on-client-session)
+`
+	assert.Equal(t, want, b.String())
+
+	if err = ss.PrintTree("unknown", &b); err == nil {
+		t.Errorf("SingleSpoe.PrintTree() error = nil, want error")
+	}
+}
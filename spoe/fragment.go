@@ -0,0 +1,75 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/strfmt"
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// StringToSPOEAgent parses the text of a single spoe-agent section, without scope.
+// Returns error on fail or if text does not hold exactly one agent.
+func StringToSPOEAgent(raw string) (*models.SpoeAgent, error) {
+	p := &spoe.Parser{}
+	if err := p.ParseData(raw); err != nil {
+		return nil, conf.NewConfError(conf.ErrValidationError, err.Error())
+	}
+	agents, err := p.SectionsGet("", parser.SPOEAgent)
+	if err != nil || len(agents) != 1 {
+		return nil, conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("expected one %s section, found %d", parser.SPOEAgent, len(agents)))
+	}
+	_, agent, err := newFragmentSpoe(p).GetAgent("", agents[0], "")
+	return agent, err
+}
+
+// SPOEAgentToString returns the text of agent as a single spoe-agent section,
+// without scope. Returns error on fail or if agent is not valid.
+func SPOEAgentToString(agent *models.SpoeAgent) (string, error) {
+	if agent == nil {
+		return "", conf.NewConfError(conf.ErrValidationError, "agent missing")
+	}
+	if err := agent.Validate(strfmt.Default); err != nil {
+		return "", conf.NewConfError(conf.ErrValidationError, err.Error())
+	}
+	p := &spoe.Parser{}
+	if err := p.ParseData(""); err != nil {
+		return "", err
+	}
+	if err := p.SectionsCreate("", parser.SPOEAgent, *agent.Name); err != nil {
+		return "", err
+	}
+	if err := newFragmentSpoe(p).createEditAgent("", agent, "", "", p); err != nil {
+		return "", err
+	}
+	return strings.TrimLeft(p.String(), "\n"), nil
+}
+
+// newFragmentSpoe returns a SingleSpoe over p, not backed by any file
+func newFragmentSpoe(p *spoe.Parser) *SingleSpoe {
+	return &SingleSpoe{
+		Parser:               p,
+		Transaction:          &conf.Transaction{},
+		parsers:              map[string]*spoe.Parser{},
+		transactionFileIndex: map[string]string{},
+	}
+}
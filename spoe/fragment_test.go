@@ -0,0 +1,62 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringToSPOEAgent(t *testing.T) {
+	raw := `spoe-agent iprep-agent
+    messages check-client-ip
+    option var-prefix iprep
+    timeout hello 2s
+    timeout idle 2m
+    timeout processing 10ms
+    use-backend agents
+    option async
+`
+	agent, err := StringToSPOEAgent(raw)
+	if err != nil {
+		t.Fatalf("StringToSPOEAgent() error = %v", err)
+	}
+	assert.Equal(t, "iprep-agent", *agent.Name)
+	assert.Equal(t, "check-client-ip", agent.Messages)
+	assert.Equal(t, "agents", agent.UseBackend)
+	assert.Equal(t, int64(2000), agent.HelloTimeout)
+
+	got, err := SPOEAgentToString(agent)
+	if err != nil {
+		t.Fatalf("SPOEAgentToString() error = %v", err)
+	}
+	roundTrip, err := StringToSPOEAgent(got)
+	if err != nil {
+		t.Fatalf("StringToSPOEAgent() error = %v", err)
+	}
+	assert.Equal(t, agent, roundTrip)
+
+	if _, err = StringToSPOEAgent("spoe-agent first\nspoe-agent second\n"); err == nil {
+		t.Errorf("StringToSPOEAgent() error = nil, want error")
+	}
+	if _, err = StringToSPOEAgent(""); err == nil {
+		t.Errorf("StringToSPOEAgent() error = nil, want error")
+	}
+	if _, err = SPOEAgentToString(nil); err == nil {
+		t.Errorf("SPOEAgentToString() error = nil, want error")
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	parser_errors "github.com/haproxytech/config-parser/v3/errors"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// Groups are declared on the scope level, agentName in the functions below is
+// the agent using the group and it must exist in the same scope.
+
+// GetSPOEGroupMessages returns the list of messages of a group.
+// Returns error on fail or if agent or group does not exist.
+func (c *SingleSpoe) GetSPOEGroupMessages(scope, agentName, groupName string, transactionID string) ([]string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return nil, err
+	}
+
+	data, err := c.getSectionDirective(scope, parser.SPOEGroup, groupName, "messages", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	messages := []string{}
+	if d, ok := data.(*types.StringC); ok {
+		messages = strings.Fields(d.Value)
+	}
+	return messages, nil
+}
+
+// SetSPOEGroupMessages replaces the list of messages of a group, all messages must
+// exist in scope. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEGroupMessages(scope, agentName, groupName string, messages []string, transactionID string, version int64) error {
+	return c.editSection(scope, parser.SPOEGroup, groupName, transactionID, version, func(p *spoe.Parser) error {
+		if err := c.checkAgentExists(scope, agentName, p); err != nil {
+			return err
+		}
+		for _, m := range messages {
+			if !c.checkSectionExists(scope, parser.SPOEMessage, m, p) {
+				return conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", parser.SPOEMessage, m))
+			}
+		}
+		return setGroupMessages(scope, groupName, messages, p)
+	})
+}
+
+func setGroupMessages(scope, groupName string, messages []string, p *spoe.Parser) error {
+	if len(messages) == 0 {
+		return p.Set(scope, parser.SPOEGroup, groupName, "messages", nil)
+	}
+	return p.Set(scope, parser.SPOEGroup, groupName, "messages", &types.StringC{Value: strings.Join(messages, " ")})
+}
+
+// AddMessageToGroup appends a message to the list of messages of a group, message must
+// exist in scope. Adding a message already in the list is a no-op.
+// One of version or transactionID is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) AddMessageToGroup(scope, agentName, groupName, messageName string, transactionID string, version int64) error {
+	return c.editSection(scope, parser.SPOEGroup, groupName, transactionID, version, func(p *spoe.Parser) error {
+		if err := c.checkAgentExists(scope, agentName, p); err != nil {
+			return err
+		}
+		if !c.checkSectionExists(scope, parser.SPOEMessage, messageName, p) {
+			return conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", parser.SPOEMessage, messageName))
+		}
+		messages := groupMessages(scope, groupName, p)
+		for _, m := range messages {
+			if m == messageName {
+				return nil
+			}
+		}
+		return setGroupMessages(scope, groupName, append(messages, messageName), p)
+	})
+}
+
+// RemoveMessageFromGroup removes a message from the list of messages of a group.
+// One of version or transactionID is mandatory.
+// Returns error on fail or if message is not in the list, nil on success.
+func (c *SingleSpoe) RemoveMessageFromGroup(scope, agentName, groupName, messageName string, transactionID string, version int64) error {
+	return c.editSection(scope, parser.SPOEGroup, groupName, transactionID, version, func(p *spoe.Parser) error {
+		if err := c.checkAgentExists(scope, agentName, p); err != nil {
+			return err
+		}
+		messages := groupMessages(scope, groupName, p)
+		for i, m := range messages {
+			if m == messageName {
+				return setGroupMessages(scope, groupName, append(messages[:i], messages[i+1:]...), p)
+			}
+		}
+		return conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("message %s not in %s %s", messageName, parser.SPOEGroup, groupName))
+	})
+}
+
+func groupMessages(scope, groupName string, p *spoe.Parser) []string {
+	data, err := p.Get(scope, parser.SPOEGroup, groupName, "messages", false)
+	if err != nil {
+		return []string{}
+	}
+	if d, ok := data.(*types.StringC); ok {
+		return strings.Fields(d.Value)
+	}
+	return []string{}
+}
+
+// GetSPOEGroupsByMessage returns groups of scope listing a message, ordered by name.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEGroupsByMessage(scope, agentName, messageName string, transactionID string) ([]*models.SpoeGroup, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return nil, err
+	}
+	groups, err := p.SectionsGet(scope, parser.SPOEGroup)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(groups)
+
+	found := []*models.SpoeGroup{}
+	for _, group := range groups {
+		if !misc.StringInSlice(messageName, groupMessages(scope, group, p)) {
+			continue
+		}
+		_, g, err := c.GetGroup(scope, group, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, g)
+	}
+	return found, nil
+}
+
+// GetSPOEAgentGroupCount returns the number of groups listed in the groups directive
+// of an agent. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentGroupCount(scope, agentName string, transactionID string) (int, error) {
+	data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, "groups", transactionID)
+	if err != nil {
+		return 0, err
+	}
+	if d, ok := data.(*types.StringC); ok {
+		return len(strings.Fields(d.Value)), nil
+	}
+	return 0, nil
+}
+
+// GetSPOEGroupMessageCount returns the number of messages listed in a group.
+// Returns error on fail or if agent or group does not exist.
+func (c *SingleSpoe) GetSPOEGroupMessageCount(scope, agentName, groupName string, transactionID string) (int, error) {
+	messages, err := c.GetSPOEGroupMessages(scope, agentName, groupName, transactionID)
+	if err != nil {
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+// FindUnusedGroups returns names of groups in scope with no messages, or whose
+// messages do not exist in scope. Returns error on fail.
+func (c *SingleSpoe) FindUnusedGroups(scope string, transactionID string) ([]string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	unused := []string{}
+	groups, err := p.SectionsGet(scope, parser.SPOEGroup)
+	if errors.Is(err, parser_errors.ErrSectionMissing) {
+		return unused, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		used := false
+		for _, m := range groupMessages(scope, group, p) {
+			if c.checkSectionExists(scope, parser.SPOEMessage, m, p) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, group)
+		}
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+// IntegrityViolation is a message listed in a group which does not exist in scope
+type IntegrityViolation struct {
+	GroupName      string
+	MissingMessage string
+	Description    string
+}
+
+// CheckReferentialIntegrity returns messages listed in groups of scope which do not
+// exist in scope, ordered by group name. Returns nil if scope can not be read.
+func (c *SingleSpoe) CheckReferentialIntegrity(scope string, transactionID string) []IntegrityViolation {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil
+	}
+	groups, err := p.SectionsGet(scope, parser.SPOEGroup)
+	if err != nil {
+		return nil
+	}
+	sort.Strings(groups)
+
+	violations := []IntegrityViolation{}
+	for _, group := range groups {
+		for _, m := range groupMessages(scope, group, p) {
+			if c.checkSectionExists(scope, parser.SPOEMessage, m, p) {
+				continue
+			}
+			violations = append(violations, IntegrityViolation{
+				GroupName:      group,
+				MissingMessage: m,
+				Description:    fmt.Sprintf("%s %s in scope %s lists %s %s which does not exist", parser.SPOEGroup, group, scope, parser.SPOEMessage, m),
+			})
+		}
+	}
+	return violations
+}
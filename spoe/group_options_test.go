@@ -0,0 +1,386 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_SetSPOEGroupMessages(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		groupName string
+		messages  []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "Should replace group messages",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			groupName: "mygroup",
+			messages:  []string{"check-client-ip"},
+			want:      []string{"check-client-ip"},
+			wantErr:   false,
+		},
+		{
+			name:      "Should remove group messages",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			groupName: "mygroup",
+			messages:  []string{},
+			want:      []string{},
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on unknown message",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			groupName: "mygroup",
+			messages:  []string{"check-client-ip", "unknown-message"},
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown group",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			groupName: "unknown-group",
+			messages:  []string{"check-client-ip"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEGroupMessages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEGroupMessages(tt.scope, tt.agentName, tt.groupName, tt.messages, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEGroupMessages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEGroupMessages(tt.scope, tt.agentName, tt.groupName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEGroupMessages() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSingleSpoe_AddRemoveMessageToGroup(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	scope, agent, group := "[ip-reputation]", "iprep-agent", "mygroup"
+
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	v, _ := ss.GetVersion("")
+	if err = ss.AddMessageToGroup(scope, agent, group, "check-client-ip", "", v); err != nil {
+		t.Errorf("SingleSpoe.AddMessageToGroup() error = %v", err)
+	}
+	v, _ = ss.GetVersion("")
+	if err = ss.AddMessageToGroup(scope, agent, group, "check-client-ip", "", v); err != nil {
+		t.Errorf("SingleSpoe.AddMessageToGroup() error = %v", err)
+	}
+	got, _ := ss.GetSPOEGroupMessages(scope, agent, group, "")
+	assert.Equal(t, []string{"mymessage", "check-client-ip"}, got)
+
+	v, _ = ss.GetVersion("")
+	if err = ss.AddMessageToGroup(scope, agent, group, "unknown-message", "", v); err == nil {
+		t.Errorf("SingleSpoe.AddMessageToGroup() error = %v, wantErr true", err)
+	}
+
+	v, _ = ss.GetVersion("")
+	if err = ss.RemoveMessageFromGroup(scope, agent, group, "mymessage", "", v); err != nil {
+		t.Errorf("SingleSpoe.RemoveMessageFromGroup() error = %v", err)
+	}
+	got, _ = ss.GetSPOEGroupMessages(scope, agent, group, "")
+	assert.Equal(t, []string{"check-client-ip"}, got)
+
+	v, _ = ss.GetVersion("")
+	if err = ss.RemoveMessageFromGroup(scope, agent, group, "mymessage", "", v); err == nil {
+		t.Errorf("SingleSpoe.RemoveMessageFromGroup() error = %v, wantErr true", err)
+	}
+}
+
+func TestSingleSpoe_FindUnusedGroups(t *testing.T) {
+	config := basicConfig + `
+spoe-group empty-group
+
+spoe-group used-group
+    messages check-client-ip unknown-message
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.FindUnusedGroups("[ip-reputation]", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.FindUnusedGroups() error = %v", err)
+		return
+	}
+	assert.Equal(t, []string{"empty-group", "mygroup"}, got)
+}
+
+func TestSingleSpoe_CheckReferentialIntegrity(t *testing.T) {
+	config := basicConfig + `
+spoe-group other-group
+    messages check-client-ip unknown-message
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got := ss.CheckReferentialIntegrity("[ip-reputation]", "")
+	want := []IntegrityViolation{
+		{
+			GroupName:      "mygroup",
+			MissingMessage: "mymessage",
+			Description:    "spoe-group mygroup in scope [ip-reputation] lists spoe-message mymessage which does not exist",
+		},
+		{
+			GroupName:      "other-group",
+			MissingMessage: "unknown-message",
+			Description:    "spoe-group other-group in scope [ip-reputation] lists spoe-message unknown-message which does not exist",
+		},
+	}
+	assert.Equal(t, want, got)
+	assert.Nil(t, ss.CheckReferentialIntegrity("[unknown]", ""))
+}
+
+func TestSingleSpoe_GetSPOEGroupCounts(t *testing.T) {
+	config := basicConfig + `
+spoe-agent group-agent
+    groups mygroup other-group
+
+spoe-group other-group
+    messages check-client-ip unknown-message
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	scope := "[ip-reputation]"
+
+	got, err := ss.GetSPOEAgentGroupCount(scope, "group-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentGroupCount() error = %v", err)
+	}
+	assert.Equal(t, 2, got)
+	got, err = ss.GetSPOEAgentGroupCount(scope, "iprep-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentGroupCount() error = %v", err)
+	}
+	assert.Equal(t, 0, got)
+	if _, err = ss.GetSPOEAgentGroupCount(scope, "unknown-agent", ""); err == nil {
+		t.Errorf("SingleSpoe.GetSPOEAgentGroupCount() error = nil, want error")
+	}
+
+	got, err = ss.GetSPOEGroupMessageCount(scope, "group-agent", "other-group", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEGroupMessageCount() error = %v", err)
+	}
+	assert.Equal(t, 2, got)
+	if _, err = ss.GetSPOEGroupMessageCount(scope, "group-agent", "unknown-group", ""); err == nil {
+		t.Errorf("SingleSpoe.GetSPOEGroupMessageCount() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_GetSPOEGroupsByMessage(t *testing.T) {
+	config := basicConfig + `
+spoe-group other-group
+    messages check-client-ip
+
+spoe-group another-group
+    messages mymessage check-client-ip
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		agentName   string
+		messageName string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:        "Should find groups of message",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			want:        []string{"another-group", "other-group"},
+		},
+		{
+			name:        "Should find groups of missing message",
+			agentName:   "iprep-agent",
+			messageName: "mymessage",
+			want:        []string{"another-group", "mygroup"},
+		},
+		{
+			name:        "Should find no groups",
+			agentName:   "iprep-agent",
+			messageName: "unknown-message",
+			want:        []string{},
+		},
+		{
+			name:        "Should fail on unknown agent",
+			agentName:   "unknown-agent",
+			messageName: "check-client-ip",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			got, err := ss.GetSPOEGroupsByMessage("[ip-reputation]", tt.agentName, tt.messageName, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.GetSPOEGroupsByMessage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			names := []string{}
+			for _, g := range got {
+				names = append(names, *g.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// hmacPrefix starts the header comment holding the HMAC of a transaction file
+const hmacPrefix = "_hmac: "
+
+// VerifyTransactionIntegrity recomputes the HMAC of the transaction file with
+// Params.EncryptionKey and compares it to the one stored in its # _hmac: header.
+// Returns nil if no key is set or the HMAC matches, ErrValidationError if the file
+// is not signed or was modified, and error on fail.
+func (c *SingleSpoe) VerifyTransactionIntegrity(id string) error {
+	if c.encryptionKey == "" {
+		return nil
+	}
+	tFile, err := c.Transaction.GetTransactionFile(id)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(tFile)
+	if err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", tFile))
+	}
+
+	stored := ""
+	lines := []string{}
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.HasPrefix(line, "# "+hmacPrefix) {
+			stored = strings.TrimSpace(strings.TrimPrefix(line, "# "+hmacPrefix))
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if stored == "" {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("transaction %s is not signed", id))
+	}
+	if !hmac.Equal([]byte(stored), []byte(c.transactionHMAC(strings.Join(lines, "")))) {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("transaction %s failed integrity check", id))
+	}
+	return nil
+}
+
+// signParser removes the # _hmac: header of p and, if sign is set and
+// Params.EncryptionKey is set, adds one with the HMAC of the configuration of p
+func (c *SingleSpoe) signParser(p *spoe.Parser, sign bool) error {
+	comments := []types.Comments{}
+	if data, err := p.Get("", parser.Comments, parser.CommentsSectionName, "#"); err == nil {
+		if d, ok := data.([]types.Comments); ok {
+			for _, comment := range d {
+				if !strings.HasPrefix(comment.Value, hmacPrefix) {
+					comments = append(comments, comment)
+				}
+			}
+		}
+	}
+	if err := setHeaderComments(p, comments); err != nil {
+		return err
+	}
+	if !sign || c.encryptionKey == "" {
+		return nil
+	}
+	comments = append(comments, types.Comments{Value: hmacPrefix + c.transactionHMAC(p.String())})
+	return setHeaderComments(p, comments)
+}
+
+// loadTransactionParser loads a transaction file in p without its # _hmac: header,
+// the HMAC is verified on Init and kept in the file only
+func (c *SingleSpoe) loadTransactionParser(p *spoe.Parser, tFile string) error {
+	if err := p.LoadData(tFile); err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", tFile))
+	}
+	return c.signParser(p, false)
+}
+
+func setHeaderComments(p *spoe.Parser, comments []types.Comments) error {
+	if len(comments) == 0 {
+		return p.Set("", parser.Comments, parser.CommentsSectionName, "#", nil)
+	}
+	return p.Set("", parser.Comments, parser.CommentsSectionName, "#", comments)
+}
+
+func (c *SingleSpoe) transactionHMAC(config string) string {
+	mac := hmac.New(sha256.New, []byte(c.encryptionKey))
+	_, _ = mac.Write([]byte(config))
+	return hex.EncodeToString(mac.Sum(nil))
+}
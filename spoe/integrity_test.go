@@ -0,0 +1,130 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_VerifyTransactionIntegrity(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = os.RemoveAll(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+		EncryptionKey:     "secret",
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	scope, agent := "[ip-reputation]", "iprep-agent"
+
+	signed, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	if err = ss.VerifyTransactionIntegrity(signed.ID); err != nil {
+		t.Errorf("SingleSpoe.VerifyTransactionIntegrity() error = %v", err)
+	}
+	if _, err = ss.SetSPOEAgentIdleTimeout(scope, agent, time.Minute, signed.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentIdleTimeout() error = %v", err)
+	}
+	if err = ss.VerifyTransactionIntegrity(signed.ID); err != nil {
+		t.Errorf("SingleSpoe.VerifyTransactionIntegrity() error = %v", err)
+	}
+	if p, _ := ss.GetParser(signed.ID); strings.Contains(p.String(), hmacPrefix) {
+		t.Errorf("transaction parser is signed: %q", p.String())
+	}
+
+	tampered, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	tFile, err := ss.Transaction.GetTransactionFile(tampered.ID)
+	if err != nil {
+		t.Fatalf("Transaction.GetTransactionFile() error = %v", err)
+	}
+	data, err := ioutil.ReadFile(tFile)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	data = []byte(strings.Replace(string(data), "use-backend agents", "use-backend rogue", 1))
+	if err = ioutil.WriteFile(tFile, data, 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+	if err = ss.VerifyTransactionIntegrity(tampered.ID); err == nil {
+		t.Errorf("SingleSpoe.VerifyTransactionIntegrity() error = nil, want error")
+	}
+
+	audit := []string{}
+	params.AuditLog = func(format string, args ...interface{}) {
+		audit = append(audit, fmt.Sprintf(format, args...))
+	}
+	reloaded, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	if !reloaded.HasParser(signed.ID) {
+		t.Errorf("SingleSpoe.HasParser(%s) = false, want true", signed.ID)
+	}
+	if p, _ := reloaded.GetParser(signed.ID); strings.Contains(p.String(), hmacPrefix) {
+		t.Errorf("reloaded transaction parser is signed: %q", p.String())
+	}
+	if reloaded.HasParser(tampered.ID) {
+		t.Errorf("SingleSpoe.HasParser(%s) = true, want false", tampered.ID)
+	}
+	if len(audit) != 1 || !strings.Contains(audit[0], tampered.ID) {
+		t.Errorf("Params.AuditLog calls = %v, want one for %s", audit, tampered.ID)
+	}
+
+	skip := false
+	params.SkipFailedTransactions = &skip
+	if _, err = newSingleSpoe(params); err == nil {
+		t.Errorf("newSingleSpoe() error = nil, want error")
+	}
+
+	if _, err = reloaded.Transaction.CommitTransaction(signed.ID); err != nil {
+		t.Fatalf("Transaction.CommitTransaction() error = %v", err)
+	}
+	data, err = ioutil.ReadFile(params.ConfigurationFile)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), hmacPrefix) {
+		t.Errorf("configuration file is signed: %q", string(data))
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-openapi/strfmt"
+	parser "github.com/haproxytech/config-parser/v3"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// ConvertToJSON returns the complete configuration as JSON of models.SpoeConfiguration,
+// scopes are sorted by name. Returns error on fail.
+func (c *SingleSpoe) ConvertToJSON(transactionID string) (json.RawMessage, error) {
+	cfg, err := c.getConfiguration(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cfg)
+}
+
+// ConvertFromJSON replaces the complete configuration with the JSON of a
+// models.SpoeConfiguration. _version of data is ignored, one of version or
+// transactionID is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) ConvertFromJSON(data json.RawMessage, transactionID string, version int64) error {
+	cfg := &models.SpoeConfiguration{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("cannot parse SPOE configuration: %s", err.Error()))
+	}
+	if c.Transaction.UseValidation {
+		if validationErr := cfg.Validate(strfmt.Default); validationErr != nil {
+			return conf.NewConfError(conf.ErrValidationError, validationErr.Error())
+		}
+	}
+	if err := checkSectionNames(cfg); err != nil {
+		return err
+	}
+
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+
+	for scope := range p.Parsers {
+		if !p.IsScope(scope) {
+			continue
+		}
+		if err := p.ScopeDelete(scope); err != nil {
+			return c.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+		}
+	}
+
+	for _, s := range cfg.Scopes {
+		if s == nil || s.Name == nil {
+			continue
+		}
+		scope := *s.Name
+		if err := p.ScopeCreate(scope); err != nil {
+			return c.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+		}
+		for _, a := range s.Agents {
+			if err := p.SectionsCreate(scope, parser.SPOEAgent, *a.Name); err != nil {
+				return c.handleError(scope, parser.SPOEAgent, *a.Name, t, transactionID == "", err)
+			}
+			if err := c.createEditAgent(scope, a, t, transactionID, p); err != nil {
+				return err
+			}
+		}
+		for _, g := range s.Groups {
+			if err := p.SectionsCreate(scope, parser.SPOEGroup, *g.Name); err != nil {
+				return c.handleError(scope, parser.SPOEGroup, *g.Name, t, transactionID == "", err)
+			}
+			if err := c.createEditGroup(scope, g, t, transactionID, p); err != nil {
+				return err
+			}
+		}
+		for _, m := range s.Messages {
+			if err := p.SectionsCreate(scope, parser.SPOEMessage, *m.Name); err != nil {
+				return c.handleError(scope, parser.SPOEMessage, *m.Name, t, transactionID == "", err)
+			}
+			if err := c.createEditMessage(scope, m, t, transactionID, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkSectionNames returns an ErrValidationError if an agent, group or message of
+// cfg has no name, these are not checked when validation is disabled
+func checkSectionNames(cfg *models.SpoeConfiguration) error {
+	for _, s := range cfg.Scopes {
+		if s == nil || s.Name == nil {
+			continue
+		}
+		for _, a := range s.Agents {
+			if a == nil || a.Name == nil {
+				return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("%s without name in scope %s", parser.SPOEAgent, *s.Name))
+			}
+		}
+		for _, g := range s.Groups {
+			if g == nil || g.Name == nil {
+				return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("%s without name in scope %s", parser.SPOEGroup, *s.Name))
+			}
+		}
+		for _, m := range s.Messages {
+			if m == nil || m.Name == nil {
+				return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("%s without name in scope %s", parser.SPOEMessage, *s.Name))
+			}
+		}
+	}
+	return nil
+}
+
+// getConfiguration returns the complete configuration as models.SpoeConfiguration
+func (c *SingleSpoe) getConfiguration(transactionID string) (*models.SpoeConfiguration, error) {
+	v, err := c.GetVersion(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := c.ListSPOEScopes(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &models.SpoeConfiguration{Version: &v, Scopes: []*models.SpoeConfigurationScope{}}
+	for _, scope := range scopes {
+		name := scope
+		_, agents, err := c.GetAgents(scope, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		_, groups, err := c.GetGroups(scope, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		_, messages, err := c.GetMessages(scope, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Scopes = append(cfg.Scopes, &models.SpoeConfigurationScope{
+			Name:     &name,
+			Agents:   agents,
+			Groups:   groups,
+			Messages: messages,
+		})
+	}
+	return cfg, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+func TestSingleSpoe_ConvertJSON(t *testing.T) {
+	newClient := func(config string) (*SingleSpoe, func()) {
+		dir, configFile, err := misc.CreateTempDir(config, true)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		transactionDir, _, err := misc.CreateTempDir("", false)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		params := Params{
+			SpoeDir:           dir,
+			TransactionDir:    transactionDir,
+			ConfigurationFile: filepath.Join(dir, configFile),
+		}
+		ss, err := newSingleSpoe(params)
+		if err != nil {
+			t.Fatalf("newSingleSpoe() error = %v", err)
+		}
+		return ss, func() {
+			_ = remove(configFile)
+			_ = remove(dir)
+			_ = remove(transactionDir)
+		}
+	}
+	src, cleanSrc := newClient(basicConfig)
+	defer cleanSrc()
+	dest, cleanDest := newClient("# _version=1\n[old-scope]\nspoe-group old-group\n    messages old\n")
+	defer cleanDest()
+
+	data, err := src.ConvertToJSON("")
+	if err != nil {
+		t.Fatalf("SingleSpoe.ConvertToJSON() error = %v", err)
+	}
+	var raw map[string]interface{}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("SingleSpoe.ConvertToJSON() invalid JSON: %v", err)
+	}
+	assert.Equal(t, float64(1), raw["_version"])
+
+	v, _ := dest.GetVersion("")
+	if err = dest.ConvertFromJSON(data, "", v); err != nil {
+		t.Fatalf("SingleSpoe.ConvertFromJSON() error = %v", err)
+	}
+	got, err := dest.ConvertToJSON("")
+	if err != nil {
+		t.Fatalf("SingleSpoe.ConvertToJSON() error = %v", err)
+	}
+	want := &models.SpoeConfiguration{}
+	_ = json.Unmarshal(data, want)
+	gotCfg := &models.SpoeConfiguration{}
+	_ = json.Unmarshal(got, gotCfg)
+	assert.Equal(t, int64(2), *gotCfg.Version)
+	assert.Equal(t, want.Scopes, gotCfg.Scopes)
+
+	v, _ = dest.GetVersion("")
+	if err = dest.ConvertFromJSON(json.RawMessage(`{"scopes": []}`), "", v); err == nil {
+		t.Errorf("SingleSpoe.ConvertFromJSON() error = %v, wantErr true", err)
+	}
+}
+
+func TestSingleSpoe_ConvertFromJSONWithoutName(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	useValidation := false
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+		UseValidation:     &useValidation,
+	}
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "Should fail on agent without name",
+			data: `{"scopes": [{"name": "[ip-reputation]", "agents": [{"use-backend": "agents"}]}]}`,
+		},
+		{
+			name: "Should fail on group without name",
+			data: `{"scopes": [{"name": "[ip-reputation]", "groups": [{}]}]}`,
+		},
+		{
+			name: "Should fail on message without name",
+			data: `{"scopes": [{"name": "[ip-reputation]", "messages": [{}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.ConvertFromJSON(json.RawMessage(tt.data), "", v)
+			if e, ok := err.(*conf.ConfError); !ok || e.Code() != conf.ErrValidationError {
+				t.Errorf("SingleSpoe.ConvertFromJSON() error = %v, want ErrValidationError", err)
+			}
+		})
+	}
+}
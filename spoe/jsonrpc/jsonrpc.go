@@ -0,0 +1,333 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package jsonrpc exposes the model and transaction methods of a SPOE
+// configuration client as JSON-RPC 2.0 methods over HTTP.
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/haproxytech/client-native/v2/spoe"
+)
+
+const version = "2.0"
+
+// JSON-RPC 2.0 error codes
+const (
+	ErrParse          = -32700
+	ErrInvalidRequest = -32600
+	ErrMethodNotFound = -32601
+	ErrInvalidParams  = -32602
+	ErrInternal       = -32603
+	ErrServer         = -32000
+)
+
+// clientMethods are the SingleSpoe methods exposed, methods writing files at given
+// paths, replacing the configuration or bypassing transactions are not
+var clientMethods = []string{ //nolint:gochecknoglobals
+	"GetVersion",
+	"GetScopes", "GetScope", "CreateScope", "DeleteScope",
+	"GetAgents", "GetAgent", "CreateAgent", "EditAgent", "DeleteAgent",
+	"GetMessages", "GetMessage", "CreateMessage", "EditMessage", "DeleteMessage",
+	"GetGroups", "GetGroup", "CreateGroup", "EditGroup", "DeleteGroup",
+}
+
+// transactionMethods are the methods of the client Transaction exposed
+var transactionMethods = []string{ //nolint:gochecknoglobals
+	"GetTransactions", "GetTransaction", "StartTransaction", "CommitTransaction", "DeleteTransaction",
+}
+
+// readOnlyPrefixes are method name prefixes of methods which do not change the
+// configuration, such methods are executed in parallel in a batch
+var readOnlyPrefixes = []string{"Get", "Has", "List", "Find", "Pretty"} //nolint:gochecknoglobals
+
+// Request is a JSON-RPC 2.0 request, params are positional and follow the
+// order of the Go method arguments. Request without ID is a notification.
+type Request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response. Methods returning a single value besides
+// error have it as result, methods returning more values have an array as result.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error is a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// Handler is an http.Handler serving JSON-RPC 2.0 requests on a SingleSpoe client.
+// Method names are the Go method names of clientMethods and transactionMethods.
+type Handler struct {
+	methods map[string]reflect.Value
+}
+
+// NewHandler returns a Handler exposing the model and transaction methods of client
+func NewHandler(client *spoe.SingleSpoe) *Handler {
+	h := &Handler{
+		methods: make(map[string]reflect.Value),
+	}
+	h.addMethods(reflect.ValueOf(client), clientMethods)
+	h.addMethods(reflect.ValueOf(client.Transaction), transactionMethods)
+	return h
+}
+
+// addMethods exposes methods of v by name, methods with arguments or return
+// values which can not be represented in JSON are skipped
+func (h *Handler) addMethods(v reflect.Value, names []string) {
+	for _, name := range names {
+		m := v.MethodByName(name)
+		if !m.IsValid() || !isExposable(m.Type()) {
+			continue
+		}
+		h.methods[name] = m
+	}
+}
+
+// Methods returns the names of exposed methods
+func (h *Handler) Methods() []string {
+	names := make([]string, 0, len(h.methods))
+	for name := range h.methods {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ServeHTTP handles a single request or a batch of requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, errorResponse(nil, ErrParse, err.Error()))
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSON(w, errorResponse(nil, ErrParse, err.Error()))
+			return
+		}
+		if len(batch) == 0 {
+			writeJSON(w, errorResponse(nil, ErrInvalidRequest, "empty batch"))
+			return
+		}
+		responses := h.handleBatch(batch)
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, responses)
+		return
+	}
+
+	res := h.handle(body)
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, res)
+}
+
+// handleBatch executes batch requests, consecutive read only requests are run in
+// parallel while requests changing the configuration are run one by one in order
+func (h *Handler) handleBatch(batch []json.RawMessage) []*Response {
+	results := make([]*Response, len(batch))
+	var wg sync.WaitGroup
+	for i, raw := range batch {
+		if !h.isReadOnly(raw) {
+			wg.Wait()
+			results[i] = h.handle(raw)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, raw json.RawMessage) {
+			defer wg.Done()
+			results[i] = h.handle(raw)
+		}(i, raw)
+	}
+	wg.Wait()
+
+	responses := make([]*Response, 0, len(results))
+	for _, res := range results {
+		if res != nil {
+			responses = append(responses, res)
+		}
+	}
+	return responses
+}
+
+func (h *Handler) isReadOnly(raw json.RawMessage) bool {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return true
+	}
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(req.Method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle executes one request, returns nil for notifications
+func (h *Handler) handle(raw json.RawMessage) *Response {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return errorResponse(nil, ErrInvalidRequest, err.Error())
+	}
+	if req.JSONRPC != version || req.Method == "" {
+		return errorResponse(req.ID, ErrInvalidRequest, "invalid request")
+	}
+
+	result, rpcErr := h.call(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr.Code, rpcErr.Message)
+	}
+	return &Response{JSONRPC: version, Result: result, ID: *req.ID}
+}
+
+func (h *Handler) call(method string, params json.RawMessage) (interface{}, *Error) {
+	m, ok := h.methods[method]
+	if !ok {
+		return nil, &Error{Code: ErrMethodNotFound, Message: fmt.Sprintf("method %s not found", method)}
+	}
+
+	args, err := decodeParams(m.Type(), params)
+	if err != nil {
+		return nil, &Error{Code: ErrInvalidParams, Message: err.Error()}
+	}
+
+	out := m.Call(args)
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if !out[n-1].IsNil() {
+			return nil, &Error{Code: ErrServer, Message: out[n-1].Interface().(error).Error()}
+		}
+		out = out[:n-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		return out[0].Interface(), nil
+	default:
+		values := make([]interface{}, 0, len(out))
+		for _, o := range out {
+			values = append(values, o.Interface())
+		}
+		return values, nil
+	}
+}
+
+func decodeParams(t reflect.Type, params json.RawMessage) ([]reflect.Value, error) {
+	var raw []json.RawMessage
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &raw); err != nil {
+			return nil, fmt.Errorf("params must be an array: %s", err.Error())
+		}
+	}
+	if len(raw) != t.NumIn() {
+		return nil, fmt.Errorf("expected %d params, got %d", t.NumIn(), len(raw))
+	}
+	args := make([]reflect.Value, 0, t.NumIn())
+	for i := 0; i < t.NumIn(); i++ {
+		v := reflect.New(t.In(i))
+		if err := json.Unmarshal(raw[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("param %d: %s", i, err.Error())
+		}
+		args = append(args, v.Elem())
+	}
+	return args, nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem() //nolint:gochecknoglobals
+
+// isExposable checks that all arguments and return values of a bound method,
+// except a trailing error, can be encoded to JSON
+func isExposable(mt reflect.Type) bool {
+	for i := 0; i < mt.NumIn(); i++ {
+		if !isJSONType(mt.In(i)) {
+			return false
+		}
+	}
+	for i := 0; i < mt.NumOut(); i++ {
+		if i == mt.NumOut()-1 && mt.Out(i) == errorType {
+			continue
+		}
+		if !isJSONType(mt.Out(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isJSONType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return isJSONType(t.Elem())
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isJSONType(t.Elem())
+	default:
+		return true
+	}
+}
+
+func errorResponse(id *json.RawMessage, code int, message string) *Response {
+	res := &Response{
+		JSONRPC: version,
+		Error:   &Error{Code: code, Message: message},
+		ID:      json.RawMessage("null"),
+	}
+	if id != nil {
+		res.ID = *id
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,233 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/spoe"
+)
+
+const testConfig = `[ip-reputation]
+spoe-agent iprep-agent
+    messages check-client-ip
+    use-backend agents
+
+spoe-message check-client-ip
+    args ip=src
+    event on-client-session
+`
+
+func newTestHandler(t *testing.T) (*Handler, func()) {
+	dir, err := ioutil.TempDir("", "spoe-jsonrpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	c, err := spoe.NewSpoe(spoe.Params{SpoeDir: dir, TransactionDir: dir})
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	if _, err = c.Create("spoe.cfg", ioutil.NopCloser(bytes.NewReader([]byte(testConfig)))); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	ss, err := c.GetSingleSpoe("spoe.cfg")
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return NewHandler(ss), cleanup
+}
+
+func post(t *testing.T, h http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	h, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantError int
+		want      interface{}
+	}{
+		{
+			name:     "Should return method result",
+			body:     `{"jsonrpc":"2.0","method":"GetVersion","params":[""],"id":1}`,
+			wantCode: http.StatusOK,
+			want:     float64(1),
+		},
+		{
+			name:      "Should fail on unknown method",
+			body:      `{"jsonrpc":"2.0","method":"Unknown","id":1}`,
+			wantCode:  http.StatusOK,
+			wantError: ErrMethodNotFound,
+		},
+		{
+			name:      "Should fail on wrong number of params",
+			body:      `{"jsonrpc":"2.0","method":"GetVersion","params":[],"id":1}`,
+			wantCode:  http.StatusOK,
+			wantError: ErrInvalidParams,
+		},
+		{
+			name:      "Should not expose methods outside of the allowlist",
+			body:      `{"jsonrpc":"2.0","method":"Save","params":["/tmp/spoe.cfg",""],"id":1}`,
+			wantCode:  http.StatusOK,
+			wantError: ErrMethodNotFound,
+		},
+		{
+			name:      "Should return method error",
+			body:      `{"jsonrpc":"2.0","method":"GetAgent","params":["[ip-reputation]","unknown",""],"id":1}`,
+			wantCode:  http.StatusOK,
+			wantError: ErrServer,
+		},
+		{
+			name:      "Should fail on invalid json",
+			body:      `{"jsonrpc":`,
+			wantCode:  http.StatusOK,
+			wantError: ErrParse,
+		},
+		{
+			name:     "Should not respond to notification",
+			body:     `{"jsonrpc":"2.0","method":"GetVersion","params":[""]}`,
+			wantCode: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(t, h, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Errorf("Handler.ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+				return
+			}
+			if rec.Code == http.StatusNoContent {
+				return
+			}
+			var res Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Errorf("Handler.ServeHTTP() invalid response: %v", err)
+				return
+			}
+			if tt.wantError != 0 {
+				if res.Error == nil || res.Error.Code != tt.wantError {
+					t.Errorf("Handler.ServeHTTP() error = %v, want code %v", res.Error, tt.wantError)
+				}
+				return
+			}
+			assert.Nil(t, res.Error)
+			assert.Equal(t, tt.want, res.Result)
+		})
+	}
+}
+
+func TestHandler_ServeHTTPBatch(t *testing.T) {
+	h, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	body := `[
+		{"jsonrpc":"2.0","method":"GetVersion","params":[""],"id":1},
+		{"jsonrpc":"2.0","method":"GetAgent","params":["[ip-reputation]","iprep-agent",""],"id":2},
+		{"jsonrpc":"2.0","method":"EditAgent","params":["[ip-reputation]",{"name":"iprep-agent","messages":"check-client-ip","use-backend":"spoe-agents"},"",1],"id":3},
+		{"jsonrpc":"2.0","method":"GetAgent","params":["[ip-reputation]","iprep-agent",""],"id":4},
+		{"jsonrpc":"2.0","method":"GetVersion","params":[""]}
+	]`
+	rec := post(t, h, body)
+	var res []Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Handler.ServeHTTP() invalid response: %v", err)
+	}
+	if len(res) != 4 {
+		t.Fatalf("Handler.ServeHTTP() got %d responses, want 4", len(res))
+	}
+	for _, r := range res {
+		assert.Nil(t, r.Error)
+	}
+	assert.Equal(t, float64(1), res[0].Result)
+	assert.Equal(t, "agents", agentUseBackend(t, res[1].Result))
+	assert.Nil(t, res[2].Result)
+	assert.Equal(t, "spoe-agents", agentUseBackend(t, res[3].Result))
+}
+
+// agentUseBackend returns use-backend of the agent in a GetAgent result
+func agentUseBackend(t *testing.T, result interface{}) interface{} {
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		t.Fatalf("GetAgent result = %v, want [version, agent]", result)
+	}
+	agent, ok := values[1].(map[string]interface{})
+	if !ok {
+		t.Fatalf("GetAgent agent = %v, want object", values[1])
+	}
+	return agent["use-backend"]
+}
+
+func TestNewHandler(t *testing.T) {
+	h, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	want := append(append([]string{}, clientMethods...), transactionMethods...)
+	assert.ElementsMatch(t, want, h.Methods())
+}
+
+func TestHandler_Transaction(t *testing.T) {
+	h, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	rec := post(t, h, `{"jsonrpc":"2.0","method":"StartTransaction","params":[1],"id":1}`)
+	var res Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.Error != nil {
+		t.Fatalf("StartTransaction error = %v, %v", err, res.Error)
+	}
+	transaction, ok := res.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("StartTransaction result = %v, want object", res.Result)
+	}
+	id, _ := json.Marshal(transaction["id"])
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"DeleteMessage","params":["[ip-reputation]","check-client-ip",` + string(id) + `,0],"id":2}`,
+		`{"jsonrpc":"2.0","method":"CommitTransaction","params":[` + string(id) + `],"id":3}`,
+	} {
+		rec = post(t, h, body)
+		res = Response{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.Error != nil {
+			t.Fatalf("Handler.ServeHTTP() error = %v, %v", err, res.Error)
+		}
+	}
+
+	rec = post(t, h, `{"jsonrpc":"2.0","method":"GetVersion","params":[""],"id":4}`)
+	res = Response{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Handler.ServeHTTP() invalid response: %v", err)
+	}
+	assert.Equal(t, float64(2), res.Result)
+}
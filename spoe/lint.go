@@ -0,0 +1,89 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"fmt"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	parser_errors "github.com/haproxytech/config-parser/v3/errors"
+)
+
+// LintWarning reports a configuration which is valid but likely a mistake
+type LintWarning struct {
+	Scope   string
+	Section parser.Section
+	Name    string
+	Message string
+}
+
+// Error implementation for LintWarning
+func (w *LintWarning) Error() string {
+	return fmt.Sprintf("%s %s %s: %s", w.Scope, w.Section, w.Name, w.Message)
+}
+
+// Lint checks the configuration of a scope for settings which are valid but
+// likely a mistake. Returns warnings found and error on fail.
+func (c *SingleSpoe) Lint(scope string, transactionID string) ([]*LintWarning, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := p.SectionsGet(scope, parser.SPOEAgent)
+	if err != nil && !errors.Is(err, parser_errors.ErrSectionMissing) {
+		return nil, err
+	}
+
+	warnings := []*LintWarning{}
+	for _, agent := range agents {
+		if w := c.lintHelloTimeout(scope, agent, transactionID); w != nil {
+			warnings = append(warnings, w)
+		}
+	}
+
+	groups, err := c.FindUnusedGroups(scope, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		warnings = append(warnings, &LintWarning{
+			Scope:   scope,
+			Section: parser.SPOEGroup,
+			Name:    group,
+			Message: "group has no existing messages",
+		})
+	}
+	return warnings, nil
+}
+
+// lintHelloTimeout warns when hello timeout of an agent exceeds its idle timeout
+func (c *SingleSpoe) lintHelloTimeout(scope, agentName, transactionID string) *LintWarning {
+	hello, err := c.GetSPOEAgentHelloTimeout(scope, agentName, transactionID)
+	if err != nil || hello == 0 {
+		return nil
+	}
+	idle, err := c.getAgentTimeout(scope, agentName, "timeout idle", transactionID)
+	if err != nil || idle == 0 || hello <= idle {
+		return nil
+	}
+	return &LintWarning{
+		Scope:   scope,
+		Section: parser.SPOEAgent,
+		Name:    agentName,
+		Message: fmt.Sprintf("timeout hello %s exceeds timeout idle %s", hello, idle),
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+const lintConfig = `# _version=1
+[lint]
+spoe-agent slow-hello
+    messages check
+    timeout hello 5m
+    timeout idle 30s
+
+spoe-agent fast-hello
+    messages check
+    timeout hello 1s
+    timeout idle 30s
+
+spoe-message check
+    args ip=src
+    event on-client-session
+
+spoe-group used
+    messages check
+
+spoe-group unused
+    messages missing
+`
+
+func TestSingleSpoe_Lint(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(lintConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.Lint("[lint]", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.Lint() error = %v", err)
+		return
+	}
+	if len(got) != 2 || got[0].Name != "slow-hello" || got[1].Name != "unused" {
+		t.Errorf("SingleSpoe.Lint() got = %v, want warnings for slow-hello and unused", got)
+	}
+	if _, err = ss.Lint("[unknown]", ""); err == nil {
+		t.Errorf("SingleSpoe.Lint() error = %v, wantErr true", err)
+	}
+}
+
+func TestSingleSpoe_LintWithoutGroups(t *testing.T) {
+	config := `# _version=1
+[no-groups]
+spoe-message check
+    args ip=src
+    event on-client-session
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	groups, err := ss.FindUnusedGroups("[no-groups]", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.FindUnusedGroups() error = %v", err)
+		return
+	}
+	if len(groups) != 0 {
+		t.Errorf("SingleSpoe.FindUnusedGroups() got = %v, want none", groups)
+	}
+	got, err := ss.Lint("[no-groups]", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.Lint() error = %v", err)
+		return
+	}
+	if len(got) != 0 {
+		t.Errorf("SingleSpoe.Lint() got = %v, want no warnings", got)
+	}
+	if _, err = ss.FindUnusedGroups("[unknown]", ""); err == nil {
+		t.Errorf("SingleSpoe.FindUnusedGroups() expected error for unknown scope")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// MergeSPOEConfigs writes to outputPath the configuration of base with all sections
+// of overlay added, sections of overlay replace sections of base with the same name.
+// Version of the result is the higher version of the two files incremented by one.
+// Returns error on fail, nil on success.
+func MergeSPOEConfigs(base, overlay string, outputPath string) error {
+	bp, err := loadConfigFile(base)
+	if err != nil {
+		return err
+	}
+	op, err := loadConfigFile(overlay)
+	if err != nil {
+		return err
+	}
+
+	for scope, sections := range op.Parsers {
+		if _, ok := bp.Parsers[scope]; !ok {
+			if err := bp.ScopeCreate(scope); err != nil {
+				return err
+			}
+		}
+		for _, section := range sectionTypes {
+			for name, data := range sections[section] {
+				bp.Parsers[scope][section][name] = data
+			}
+		}
+	}
+
+	bv, err := configVersion(bp)
+	if err != nil {
+		return err
+	}
+	ov, err := configVersion(op)
+	if err != nil {
+		return err
+	}
+	if ov.Value > bv.Value {
+		bv.Value = ov.Value
+	}
+	bv.Value++
+
+	if err := bp.Save(outputPath); err != nil {
+		return conf.NewConfError(conf.ErrErrorChangingConfig, fmt.Sprintf("cannot write %s: %s", outputPath, err.Error()))
+	}
+	return nil
+}
+
+// loadConfigFile returns a parser loaded from a configuration file
+func loadConfigFile(filename string) (*spoe.Parser, error) {
+	data, err := readConfigFile(filename)
+	if err != nil {
+		return nil, conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", filename))
+	}
+	p := &spoe.Parser{}
+	if err := p.ParseData(data); err != nil {
+		return nil, conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot parse %s: %s", filename, err.Error()))
+	}
+	return p, nil
+}
+
+// configVersion returns the version of p, which can be changed in place
+func configVersion(p *spoe.Parser) (*types.ConfigVersion, error) {
+	data, err := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
+	if err != nil {
+		return nil, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version: %s", err.Error()))
+	}
+	ver, ok := data.(*types.ConfigVersion)
+	if !ok {
+		return nil, conf.NewConfError(conf.ErrCannotReadVersion, "cannot read version")
+	}
+	return ver, nil
+}
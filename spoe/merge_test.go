@@ -0,0 +1,83 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestMergeSPOEConfigs(t *testing.T) {
+	overlay := `# _version=4
+[ip-reputation]
+spoe-agent iprep-agent
+    messages check-client-ip
+    use-backend other-agents
+
+[other]
+spoe-group other-group
+    messages other
+`
+	dir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	base := filepath.Join(dir, "base.cfg")
+	overlayFile := filepath.Join(dir, "overlay.cfg")
+	output := filepath.Join(dir, "output.cfg")
+	if err = ioutil.WriteFile(base, []byte(basicConfig), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+	if err = ioutil.WriteFile(overlayFile, []byte(overlay), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+
+	if err = MergeSPOEConfigs(base, overlayFile, output); err != nil {
+		t.Fatalf("MergeSPOEConfigs() error = %v", err)
+	}
+	got, err := loadConfigFile(output)
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	ver, _ := configVersion(got)
+	assert.Equal(t, int64(5), ver.Value)
+	ss := newFragmentSpoe(got)
+	_, agent, err := ss.GetAgent("[ip-reputation]", "iprep-agent", "")
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetAgent() error = %v", err)
+	}
+	assert.Equal(t, "other-agents", agent.UseBackend)
+	assert.Equal(t, int64(0), agent.HelloTimeout)
+	if _, _, err = ss.GetMessage("[ip-reputation]", "check-client-ip", ""); err != nil {
+		t.Errorf("SingleSpoe.GetMessage() error = %v", err)
+	}
+	if _, _, err = ss.GetGroup("[other]", "other-group", ""); err != nil {
+		t.Errorf("SingleSpoe.GetGroup() error = %v", err)
+	}
+
+	if err = MergeSPOEConfigs(base, filepath.Join(dir, "missing.cfg"), output); err == nil {
+		t.Errorf("MergeSPOEConfigs() error = nil, want error")
+	}
+}
@@ -0,0 +1,521 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/strfmt"
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	spoe_types "github.com/haproxytech/config-parser/v3/spoe/types"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// messageEvents are the events a message can be sent on
+var messageEvents = []string{ //nolint:gochecknoglobals
+	models.SpoeMessageEventNameOnClientSession,
+	models.SpoeMessageEventNameOnServerSession,
+	models.SpoeMessageEventNameOnFrontendTCPRequest,
+	models.SpoeMessageEventNameOnBackendTCPRequest,
+	models.SpoeMessageEventNameOnTCPResponse,
+	models.SpoeMessageEventNameOnFrontendHTTPRequest,
+	models.SpoeMessageEventNameOnBackendHTTPRequest,
+	models.SpoeMessageEventNameOnHTTPResponse,
+}
+
+// Messages are declared on the scope level, agentName in the functions below is
+// the agent handling the message and it must exist in the same scope.
+
+// GetSPOEMessageArgs returns the arguments of a message. Argument types are
+// not part of the configuration, HAProxy infers them from the sample fetches.
+// Returns error on fail or if agent or message does not exist.
+func (c *SingleSpoe) GetSPOEMessageArgs(scope, agentName, messageName string, transactionID string) (models.SpoeMessageArgs, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return nil, err
+	}
+
+	data, err := c.getSectionDirective(scope, parser.SPOEMessage, messageName, "args", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	args := models.SpoeMessageArgs{}
+	if d, ok := data.(*types.StringC); ok {
+		args = parseMessageArgs(d.Value)
+	}
+	return args, nil
+}
+
+// GetSPOEMessageArgCount returns the number of arguments of a message.
+// Returns error on fail or if agent or message does not exist.
+func (c *SingleSpoe) GetSPOEMessageArgCount(scope, agentName, messageName string, transactionID string) (int, error) {
+	args, err := c.GetSPOEMessageArgs(scope, agentName, messageName, transactionID)
+	if err != nil {
+		return 0, err
+	}
+	return len(args), nil
+}
+
+// SetSPOEMessageArgs replaces the arguments of a message, an empty list removes
+// the args directive. With Params.MaxMessageArgs set, longer lists are rejected
+// with ErrSectionLimitExceeded. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEMessageArgs(scope, agentName, messageName string, args models.SpoeMessageArgs, transactionID string, version int64) error {
+	if c.maxMessageArgs > 0 && len(args) > c.maxMessageArgs {
+		return conf.NewConfError(conf.ErrSectionLimitExceeded, fmt.Sprintf("%s %s has %d arguments, limit is %d", parser.SPOEMessage, messageName, len(args), c.maxMessageArgs))
+	}
+	if c.Transaction.UseValidation {
+		if validationErr := args.Validate(strfmt.Default); validationErr != nil {
+			return conf.NewConfError(conf.ErrValidationError, validationErr.Error())
+		}
+	}
+
+	return c.editSection(scope, parser.SPOEMessage, messageName, transactionID, version, func(p *spoe.Parser) error {
+		if err := c.checkAgentExists(scope, agentName, p); err != nil {
+			return err
+		}
+		return setMessageArgs(scope, messageName, args, p)
+	})
+}
+
+// CreateSPOEMessageWithArgs creates a message in scope with args as its arguments,
+// replacing args of message, in a single save. Nothing is created if message or
+// args are not valid. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) CreateSPOEMessageWithArgs(scope, agentName string, message *models.SpoeMessage, args []*models.SpoeMessageArg, transactionID string, version int64) error {
+	if message == nil || message.Name == nil {
+		return conf.NewConfError(conf.ErrValidationError, "spoe message not initialized")
+	}
+	if c.maxMessageArgs > 0 && len(args) > c.maxMessageArgs {
+		return conf.NewConfError(conf.ErrSectionLimitExceeded, fmt.Sprintf("%s %s has %d arguments, limit is %d", parser.SPOEMessage, *message.Name, len(args), c.maxMessageArgs))
+	}
+	if c.Transaction.UseValidation {
+		if validationErr := message.Validate(strfmt.Default); validationErr != nil {
+			return conf.NewConfError(conf.ErrValidationError, validationErr.Error())
+		}
+		if validationErr := models.SpoeMessageArgs(args).Validate(strfmt.Default); validationErr != nil {
+			return conf.NewConfError(conf.ErrValidationError, validationErr.Error())
+		}
+	}
+
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return c.handleError(scope, parser.SPOEAgent, agentName, t, transactionID == "", err)
+	}
+	if c.checkSectionExists(scope, parser.SPOEMessage, *message.Name, p) {
+		e := conf.NewConfError(conf.ErrObjectAlreadyExists, fmt.Sprintf("%s %s already exists", parser.SPOEMessage, *message.Name))
+		return c.handleError(scope, parser.SPOEMessage, *message.Name, t, transactionID == "", e)
+	}
+
+	if err = p.SectionsCreate(scope, parser.SPOEMessage, *message.Name); err != nil {
+		return c.handleError(scope, parser.SPOEMessage, *message.Name, t, transactionID == "", err)
+	}
+	if err = c.createEditMessage(scope, message, t, transactionID, p); err != nil {
+		return err
+	}
+	if err = setMessageArgs(scope, *message.Name, args, p); err != nil {
+		return c.handleError(scope, parser.SPOEMessage, *message.Name, t, transactionID == "", err)
+	}
+
+	return c.Transaction.SaveData(p, t, transactionID == "")
+}
+
+func setMessageArgs(scope, messageName string, args models.SpoeMessageArgs, p *spoe.Parser) error {
+	if len(args) == 0 {
+		return p.Set(scope, parser.SPOEMessage, messageName, "args", nil)
+	}
+	return p.Set(scope, parser.SPOEMessage, messageName, "args", &types.StringC{Value: formatMessageArgs(args)})
+}
+
+// parseMessageArgs parses the value of the args directive, a list of [name=]sample entries
+func parseMessageArgs(value string) models.SpoeMessageArgs {
+	args := models.SpoeMessageArgs{}
+	for _, field := range strings.Fields(value) {
+		arg := &models.SpoeMessageArg{}
+		expr := field
+		// an equal sign after the start of the sample expression belongs to it
+		if i := strings.Index(field, "="); i > 0 && !strings.ContainsAny(field[:i], "(,") {
+			arg.Name = field[:i]
+			expr = field[i+1:]
+		}
+		arg.Expr = &expr
+		args = append(args, arg)
+	}
+	return args
+}
+
+func formatMessageArgs(args models.SpoeMessageArgs) string {
+	fields := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil || arg.Expr == nil {
+			continue
+		}
+		if arg.Name != "" {
+			fields = append(fields, arg.Name+"="+*arg.Expr)
+		} else {
+			fields = append(fields, *arg.Expr)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// GetSPOEMessageEvent returns the event a message is sent on, empty string if not set.
+// Returns error on fail or if agent or message does not exist.
+func (c *SingleSpoe) GetSPOEMessageEvent(scope, agentName, messageName string, transactionID string) (string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return "", err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return "", err
+	}
+
+	data, err := c.getSectionDirective(scope, parser.SPOEMessage, messageName, "event", transactionID)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := data.(*spoe_types.Event); ok {
+		return d.Name, nil
+	}
+	return "", nil
+}
+
+// SetSPOEMessageEvent sets the event a message is sent on, condition of the event
+// is kept. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEMessageEvent(scope, agentName, messageName, event string, transactionID string, version int64) error {
+	if !misc.StringInSlice(event, messageEvents) {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown event %s, must be one of: %s", event, strings.Join(messageEvents, ", ")))
+	}
+
+	return c.editSection(scope, parser.SPOEMessage, messageName, transactionID, version, func(p *spoe.Parser) error {
+		if err := c.checkAgentExists(scope, agentName, p); err != nil {
+			return err
+		}
+		d := &spoe_types.Event{Name: event}
+		if data, err := p.Get(scope, parser.SPOEMessage, messageName, "event", false); err == nil {
+			if e, ok := data.(*spoe_types.Event); ok {
+				d.Cond = e.Cond
+				d.CondTest = e.CondTest
+				d.Comment = e.Comment
+			}
+		}
+		return p.Set(scope, parser.SPOEMessage, messageName, "event", d)
+	})
+}
+
+// GetSPOEMessageCondition returns the condition of the event a message is sent on,
+// nil if the event is unconditional. Returns error on fail or if agent or message does not exist.
+func (c *SingleSpoe) GetSPOEMessageCondition(scope, agentName, messageName string, transactionID string) (*models.SpoeCondition, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return nil, err
+	}
+
+	data, err := c.getSectionDirective(scope, parser.SPOEMessage, messageName, "event", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := data.(*spoe_types.Event); ok && d.Cond != "" {
+		return &models.SpoeCondition{Type: d.Cond, Expression: d.CondTest}, nil
+	}
+	return nil, nil
+}
+
+// SetSPOEMessageCondition sets the condition of the event a message is sent on, a nil
+// condition or one without type removes it. Message must have an event.
+// One of version or transactionID is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) SetSPOEMessageCondition(scope, agentName, messageName string, condition *models.SpoeCondition, transactionID string, version int64) error {
+	if condition != nil {
+		if err := condition.Validate(strfmt.Default); err != nil {
+			return conf.NewConfError(conf.ErrValidationError, err.Error())
+		}
+		if condition.Type != "" && strings.TrimSpace(condition.Expression) == "" {
+			return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("condition %s requires an expression", condition.Type))
+		}
+		if condition.Type == "" && condition.Expression != "" {
+			return conf.NewConfError(conf.ErrValidationError, "condition expression requires a type")
+		}
+	}
+
+	return c.editSection(scope, parser.SPOEMessage, messageName, transactionID, version, func(p *spoe.Parser) error {
+		if err := c.checkAgentExists(scope, agentName, p); err != nil {
+			return err
+		}
+		data, err := p.Get(scope, parser.SPOEMessage, messageName, "event", false)
+		if err != nil {
+			return conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s has no event", parser.SPOEMessage, messageName))
+		}
+		e, ok := data.(*spoe_types.Event)
+		if !ok {
+			return conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s has no event", parser.SPOEMessage, messageName))
+		}
+		d := &spoe_types.Event{Name: e.Name, Comment: e.Comment}
+		if condition != nil {
+			d.Cond = condition.Type
+			d.CondTest = condition.Expression
+		}
+		return p.Set(scope, parser.SPOEMessage, messageName, "event", d)
+	})
+}
+
+// GetAllSPOEMessages returns messages of all agents in all scopes, keyed by
+// {scope}/{agentName}. Messages of an agent are the ones listed in its messages
+// directive and in its groups, messages which do not exist are skipped.
+// Returns error on fail.
+func (c *SingleSpoe) GetAllSPOEMessages(transactionID string) (map[string][]*models.SpoeMessage, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := c.ListSPOEScopes(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[string][]*models.SpoeMessage)
+	for _, scope := range scopes {
+		agents, err := p.SectionsGet(scope, parser.SPOEAgent)
+		if err != nil {
+			continue
+		}
+		for _, agent := range agents {
+			messages := []*models.SpoeMessage{}
+			for _, name := range agentMessageNames(scope, agent, p) {
+				if !c.checkSectionExists(scope, parser.SPOEMessage, name, p) {
+					continue
+				}
+				_, m, err := c.GetMessage(scope, name, transactionID)
+				if err != nil {
+					return nil, err
+				}
+				messages = append(messages, m)
+			}
+			all[scope+"/"+agent] = messages
+		}
+	}
+	return all, nil
+}
+
+// FindDuplicateMessageNames returns messages used by more than one agent in scope,
+// directly or through groups, keyed by message name with sorted names of the agents.
+// Returns error on fail or if scope does not exist.
+func (c *SingleSpoe) FindDuplicateMessageNames(scope string, transactionID string) (map[string][]string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := p.SectionsGet(scope, parser.SPOEAgent)
+	if err != nil {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("scope %s does not exist", scope))
+	}
+
+	users := map[string][]string{}
+	for _, agent := range agents {
+		for _, m := range agentMessageNames(scope, agent, p) {
+			users[m] = append(users[m], agent)
+		}
+	}
+	duplicates := map[string][]string{}
+	for m, agents := range users {
+		if len(agents) > 1 {
+			sort.Strings(agents)
+			duplicates[m] = agents
+		}
+	}
+	return duplicates, nil
+}
+
+// GetSPOEMessageCount returns the number of messages used by an agent, directly or
+// through its groups. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEMessageCount(scope, agentName string, transactionID string) (int, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return 0, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return 0, err
+	}
+	return len(agentMessageNames(scope, agentName, p)), nil
+}
+
+// DeleteSPOEMessages deletes messages used by an agent, directly or through its groups,
+// and saves once. Messages which do not exist or are not used by the agent are skipped,
+// or with strict fail the deletion before any message is deleted.
+// One of version or transactionID is mandatory. Returns number of messages deleted
+// and error on fail.
+func (c *SingleSpoe) DeleteSPOEMessages(scope, agentName string, messageNames []string, strict bool, transactionID string, version int64) (int, error) {
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return 0, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return 0, c.handleError(scope, parser.SPOEAgent, agentName, t, transactionID == "", err)
+	}
+
+	used := agentMessageNames(scope, agentName, p)
+	names := []string{}
+	for _, name := range messageNames {
+		if misc.StringInSlice(name, used) && c.checkSectionExists(scope, parser.SPOEMessage, name, p) {
+			if !misc.StringInSlice(name, names) {
+				names = append(names, name)
+			}
+			continue
+		}
+		if strict {
+			e := conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s of %s %s does not exist", parser.SPOEMessage, name, parser.SPOEAgent, agentName))
+			return 0, c.handleError(scope, parser.SPOEMessage, name, t, transactionID == "", e)
+		}
+	}
+
+	count := 0
+	for _, name := range names {
+		if err := p.SectionsDelete(scope, parser.SPOEMessage, name); err != nil {
+			return 0, c.handleError(scope, parser.SPOEMessage, name, t, transactionID == "", err)
+		}
+		count++
+	}
+
+	// nothing changed, do not commit an implicit transaction
+	if count == 0 {
+		if transactionID == "" {
+			_ = c.Transaction.DeleteTransaction(t)
+		}
+		return 0, nil
+	}
+
+	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteSPOEMessageByIndex deletes the message at index of the messages used by an
+// agent, directly or through its groups, in the order they are listed. One of version
+// or transactionID is mandatory. Returns error on fail or if index is out of range.
+func (c *SingleSpoe) DeleteSPOEMessageByIndex(scope, agentName string, index int, transactionID string, version int64) error {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return err
+	}
+	names := agentMessageNames(scope, agentName, p)
+	if index < 0 || index >= len(names) {
+		return conf.NewConfError(conf.ErrObjectIndexOutOfRange, fmt.Sprintf("%s with index %d of %s %s out of range", parser.SPOEMessage, index, parser.SPOEAgent, agentName))
+	}
+	return c.DeleteMessage(scope, names[index], transactionID, version)
+}
+
+// ListMessagesByEvent returns messages sent by an agent, from its messages directive
+// and its groups, which are sent on event. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) ListMessagesByEvent(scope, agentName, event string, transactionID string) ([]*models.SpoeMessage, error) {
+	if !misc.StringInSlice(event, messageEvents) {
+		return nil, conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("unknown event %s, must be one of: %s", event, strings.Join(messageEvents, ", ")))
+	}
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return nil, err
+	}
+
+	messages := []*models.SpoeMessage{}
+	for _, name := range agentMessageNames(scope, agentName, p) {
+		data, err := p.Get(scope, parser.SPOEMessage, name, "event", false)
+		if err != nil {
+			continue
+		}
+		if d, ok := data.(*spoe_types.Event); !ok || d.Name != event {
+			continue
+		}
+		_, message, err := c.GetMessage(scope, name, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// ListUsedEvents returns sorted unique events of messages sent by an agent, from its
+// messages directive and its groups. Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) ListUsedEvents(scope, agentName string, transactionID string) ([]string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkAgentExists(scope, agentName, p); err != nil {
+		return nil, err
+	}
+
+	events := []string{}
+	for _, name := range agentMessageNames(scope, agentName, p) {
+		data, err := p.Get(scope, parser.SPOEMessage, name, "event", false)
+		if err != nil {
+			continue
+		}
+		if d, ok := data.(*spoe_types.Event); ok && !misc.StringInSlice(d.Name, events) {
+			events = append(events, d.Name)
+		}
+	}
+	sort.Strings(events)
+	return events, nil
+}
+
+// agentMessageNames returns unique names of messages an agent sends, from its
+// messages directive followed by the messages of its groups
+func agentMessageNames(scope, agentName string, p *spoe.Parser) []string {
+	names := []string{}
+	add := func(list string) {
+		for _, name := range strings.Fields(list) {
+			if !misc.StringInSlice(name, names) {
+				names = append(names, name)
+			}
+		}
+	}
+	if data, err := p.Get(scope, parser.SPOEAgent, agentName, "messages", false); err == nil {
+		if d, ok := data.(*types.StringC); ok {
+			add(d.Value)
+		}
+	}
+	if data, err := p.Get(scope, parser.SPOEAgent, agentName, "groups", false); err == nil {
+		if d, ok := data.(*types.StringC); ok {
+			for _, group := range strings.Fields(d.Value) {
+				add(strings.Join(groupMessages(scope, group, p), " "))
+			}
+		}
+	}
+	return names
+}
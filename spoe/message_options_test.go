@@ -0,0 +1,923 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+func TestSingleSpoe_SetSPOEMessageArgs(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		messageName string
+		args        models.SpoeMessageArgs
+		want        models.SpoeMessageArgs
+		wantErr     bool
+	}{
+		{
+			name:        "Should replace message arguments",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			args: models.SpoeMessageArgs{
+				{Name: "ip", Expr: misc.StringP("src")},
+				{Expr: misc.StringP("req.hdr(host),lower")},
+			},
+			want: models.SpoeMessageArgs{
+				{Name: "ip", Expr: misc.StringP("src")},
+				{Expr: misc.StringP("req.hdr(host),lower")},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "Should fail on invalid argument name",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			args: models.SpoeMessageArgs{
+				{Name: "client ip", Expr: misc.StringP("src")},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "Should fail on unknown agent",
+			scope:       "[ip-reputation]",
+			agentName:   "unknown-agent",
+			messageName: "check-client-ip",
+			args: models.SpoeMessageArgs{
+				{Name: "ip", Expr: misc.StringP("src")},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "Should fail on unknown message",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "unknown-message",
+			args: models.SpoeMessageArgs{
+				{Name: "ip", Expr: misc.StringP("src")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEMessageArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEMessageArgs(tt.scope, tt.agentName, tt.messageName, tt.args, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEMessageArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEMessageArgs(tt.scope, tt.agentName, tt.messageName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEMessageArgs() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEMessageEvent(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		messageName string
+		event       string
+		wantErr     bool
+	}{
+		{
+			name:        "Should change message event",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			event:       "on-frontend-http-request",
+			wantErr:     false,
+		},
+		{
+			name:        "Should fail on unknown event",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			event:       "on-client-connect",
+			wantErr:     true,
+		},
+		{
+			name:        "Should fail on unknown message",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "unknown-message",
+			event:       "on-client-session",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEMessageEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEMessageEvent(tt.scope, tt.agentName, tt.messageName, tt.event, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEMessageEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEMessageEvent(tt.scope, tt.agentName, tt.messageName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEMessageEvent() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.event, got)
+			_, m, err := ss.GetMessage(tt.scope, tt.messageName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetMessage() error = %v", err)
+				return
+			}
+			assert.Equal(t, "! { src -f /etc/haproxy/whitelist.lst }", m.Event.CondTest)
+		})
+	}
+}
+
+func TestSingleSpoe_GetAllSPOEMessages(t *testing.T) {
+	config := basicConfig + `
+[other]
+spoe-agent other-agent
+    groups other-group
+
+spoe-group other-group
+    messages first second
+
+spoe-message first
+    args src
+    event on-client-session
+
+spoe-message second
+    args dst
+    event on-server-session
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.GetAllSPOEMessages("")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetAllSPOEMessages() error = %v", err)
+		return
+	}
+	names := map[string][]string{}
+	for key, messages := range got {
+		names[key] = []string{}
+		for _, m := range messages {
+			names[key] = append(names[key], *m.Name)
+		}
+	}
+	want := map[string][]string{
+		"[ip-reputation]/iprep-agent": {"check-client-ip"},
+		"[other]/other-agent":         {"first", "second"},
+	}
+	assert.Equal(t, want, names)
+}
+
+func TestSingleSpoe_DeleteSPOEMessageByIndex(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		index     int
+		wantErr   bool
+	}{
+		{name: "Should fail on index out of range", scope: "[ip-reputation]", agentName: "iprep-agent", index: 1, wantErr: true},
+		{name: "Should fail on negative index", scope: "[ip-reputation]", agentName: "iprep-agent", index: -1, wantErr: true},
+		{name: "Should fail on unknown agent", scope: "[ip-reputation]", agentName: "unknown-agent", index: 0, wantErr: true},
+		{name: "Should delete message", scope: "[ip-reputation]", agentName: "iprep-agent", index: 0, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.DeleteSPOEMessageByIndex() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.DeleteSPOEMessageByIndex(tt.scope, tt.agentName, tt.index, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.DeleteSPOEMessageByIndex() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, _, err := ss.GetMessage(tt.scope, "check-client-ip", ""); err == nil {
+				t.Errorf("SingleSpoe.GetMessage() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEMessageCondition(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		messageName string
+		condition   *models.SpoeCondition
+		want        *models.SpoeCondition
+		wantErr     bool
+	}{
+		{
+			name:        "Should set unless condition",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			condition:   &models.SpoeCondition{Type: "unless", Expression: "{ src 10.0.0.0/8 }"},
+			want:        &models.SpoeCondition{Type: "unless", Expression: "{ src 10.0.0.0/8 }"},
+			wantErr:     false,
+		},
+		{
+			name:        "Should remove condition",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			condition:   nil,
+			want:        nil,
+			wantErr:     false,
+		},
+		{
+			name:        "Should fail on missing expression",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			condition:   &models.SpoeCondition{Type: "if"},
+			wantErr:     true,
+		},
+		{
+			name:        "Should fail on invalid type",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "check-client-ip",
+			condition:   &models.SpoeCondition{Type: "when", Expression: "{ src 10.0.0.0/8 }"},
+			wantErr:     true,
+		},
+		{
+			name:        "Should fail on unknown message",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messageName: "unknown-message",
+			condition:   &models.SpoeCondition{Type: "if", Expression: "{ src 10.0.0.0/8 }"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEMessageCondition() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.SetSPOEMessageCondition(tt.scope, tt.agentName, tt.messageName, tt.condition, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEMessageCondition() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEMessageCondition(tt.scope, tt.agentName, tt.messageName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEMessageCondition() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			event, _ := ss.GetSPOEMessageEvent(tt.scope, tt.agentName, tt.messageName, "")
+			assert.Equal(t, "on-client-session", event)
+		})
+	}
+}
+
+func TestSingleSpoe_FindDuplicateMessageNames(t *testing.T) {
+	config := basicConfig + `
+spoe-agent other-agent
+    messages check-client-ip
+
+spoe-agent group-agent
+    groups mygroup
+    messages mymessage
+
+spoe-agent another-agent
+    groups mygroup
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.FindDuplicateMessageNames("[ip-reputation]", "")
+	if err != nil {
+		t.Fatalf("SingleSpoe.FindDuplicateMessageNames() error = %v", err)
+	}
+	want := map[string][]string{
+		"check-client-ip": {"iprep-agent", "other-agent"},
+		"mymessage":       {"another-agent", "group-agent"},
+	}
+	assert.Equal(t, want, got)
+
+	if _, err = ss.FindDuplicateMessageNames("[unknown]", ""); err == nil {
+		t.Errorf("SingleSpoe.FindDuplicateMessageNames() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_ListMessagesByEvent(t *testing.T) {
+	config := basicConfig + `
+spoe-agent group-agent
+    messages check-client-ip
+    groups server-group
+
+spoe-message check-server
+    event on-server-session
+
+spoe-message check-server-session
+    event on-server-session if { src 127.0.0.1 }
+
+spoe-group server-group
+    messages check-server check-server-session unknown-message
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		agentName string
+		event     string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "Should list messages of groups on event",
+			agentName: "group-agent",
+			event:     "on-server-session",
+			want:      []string{"check-server", "check-server-session"},
+		},
+		{
+			name:      "Should list messages of agent on event",
+			agentName: "group-agent",
+			event:     "on-client-session",
+			want:      []string{"check-client-ip"},
+		},
+		{
+			name:      "Should list nothing for agent without messages on event",
+			agentName: "iprep-agent",
+			event:     "on-server-session",
+			want:      []string{},
+		},
+		{
+			name:      "Should fail on unknown event",
+			agentName: "group-agent",
+			event:     "on-nothing",
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			agentName: "unknown-agent",
+			event:     "on-server-session",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			got, err := ss.ListMessagesByEvent("[ip-reputation]", tt.agentName, tt.event, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.ListMessagesByEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			names := []string{}
+			for _, m := range got {
+				names = append(names, *m.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestSingleSpoe_MaxMessageArgs(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+		MaxMessageArgs:    2,
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	scope, agent, message := "[ip-reputation]", "iprep-agent", "check-client-ip"
+
+	got, err := ss.GetSPOEMessageArgCount(scope, agent, message, "")
+	if err != nil {
+		t.Errorf("SingleSpoe.GetSPOEMessageArgCount() error = %v", err)
+	}
+	assert.Equal(t, 1, got)
+
+	args := models.SpoeMessageArgs{
+		{Name: "ip", Expr: misc.StringP("src")},
+		{Name: "port", Expr: misc.StringP("src_port")},
+		{Name: "host", Expr: misc.StringP("req.hdr(host)")},
+	}
+	v, _ := ss.GetVersion("")
+	err = ss.SetSPOEMessageArgs(scope, agent, message, args, "", v)
+	var confErr *conf.ConfError
+	if !errors.As(err, &confErr) || confErr.Code() != conf.ErrSectionLimitExceeded {
+		t.Errorf("SingleSpoe.SetSPOEMessageArgs() error = %v, want ErrSectionLimitExceeded", err)
+	}
+	got, _ = ss.GetSPOEMessageArgCount(scope, agent, message, "")
+	assert.Equal(t, 1, got)
+
+	v, _ = ss.GetVersion("")
+	if err = ss.SetSPOEMessageArgs(scope, agent, message, args[:2], "", v); err != nil {
+		t.Errorf("SingleSpoe.SetSPOEMessageArgs() error = %v", err)
+	}
+	got, _ = ss.GetSPOEMessageArgCount(scope, agent, message, "")
+	assert.Equal(t, 2, got)
+
+	if _, err = ss.GetSPOEMessageArgCount(scope, agent, "unknown-message", ""); err == nil {
+		t.Errorf("SingleSpoe.GetSPOEMessageArgCount() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_ListUsedEvents(t *testing.T) {
+	config := basicConfig + `
+spoe-agent group-agent
+    messages check-client-ip
+    groups server-group
+
+spoe-message check-server
+    event on-server-session
+
+spoe-message check-client
+    event on-client-session
+
+spoe-group server-group
+    messages check-server check-client unknown-message
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.ListUsedEvents("[ip-reputation]", "group-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.ListUsedEvents() error = %v", err)
+	}
+	assert.Equal(t, []string{"on-client-session", "on-server-session"}, got)
+
+	got, err = ss.ListUsedEvents("[ip-reputation]", "iprep-agent", "")
+	if err != nil {
+		t.Errorf("SingleSpoe.ListUsedEvents() error = %v", err)
+	}
+	assert.Equal(t, []string{"on-client-session"}, got)
+
+	if _, err = ss.ListUsedEvents("[ip-reputation]", "unknown-agent", ""); err == nil {
+		t.Errorf("SingleSpoe.ListUsedEvents() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_DeleteSPOEMessages(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		messages    []string
+		strict      bool
+		want        int
+		wantVersion int64
+		wantErr     bool
+	}{
+		{
+			name:        "Should fail on missing message in strict mode",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messages:    []string{"check-client-ip", "unknown-message"},
+			strict:      true,
+			wantVersion: 1,
+			wantErr:     true,
+		},
+		{
+			name:        "Should fail on unknown agent",
+			scope:       "[ip-reputation]",
+			agentName:   "unknown-agent",
+			messages:    []string{"check-client-ip"},
+			wantVersion: 1,
+			wantErr:     true,
+		},
+		{
+			name:        "Should delete messages and skip missing ones",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messages:    []string{"check-client-ip", "unknown-message"},
+			want:        1,
+			wantVersion: 2,
+			wantErr:     false,
+		},
+		{
+			name:        "Should delete nothing and keep version",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			messages:    []string{"check-client-ip"},
+			want:        0,
+			wantVersion: 2,
+			wantErr:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.DeleteSPOEMessages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			got, err := ss.DeleteSPOEMessages(tt.scope, tt.agentName, tt.messages, tt.strict, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.DeleteSPOEMessages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SingleSpoe.DeleteSPOEMessages() got = %v, want %v", got, tt.want)
+			}
+			if v, _ = ss.GetVersion(""); v != tt.wantVersion {
+				t.Errorf("SingleSpoe.GetVersion() got = %v, want %v", v, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_DeleteSPOEMessagesInTransaction(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	ss, err := newSingleSpoe(Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	})
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	scope := "[ip-reputation]"
+	if err = ss.CreateMessage(scope, &models.SpoeMessage{Name: misc.StringP("unused-message")}, tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.CreateMessage() error = %v", err)
+	}
+
+	// strict deletion checks all messages before deleting any
+	if _, err = ss.DeleteSPOEMessages(scope, "iprep-agent", []string{"check-client-ip", "unknown-message"}, true, tr.ID, 0); err == nil {
+		t.Errorf("SingleSpoe.DeleteSPOEMessages() error = nil, want error")
+	}
+	if _, _, err = ss.GetMessage(scope, "check-client-ip", tr.ID); err != nil {
+		t.Errorf("SingleSpoe.GetMessage() error = %v, want check-client-ip kept", err)
+	}
+	// messages not used by the agent are not deleted
+	got, err := ss.DeleteSPOEMessages(scope, "iprep-agent", []string{"unused-message", "check-client-ip"}, false, tr.ID, 0)
+	if err != nil || got != 1 {
+		t.Errorf("SingleSpoe.DeleteSPOEMessages() got = %v, error = %v, want 1", got, err)
+	}
+	if _, _, err = ss.GetMessage(scope, "unused-message", tr.ID); err != nil {
+		t.Errorf("SingleSpoe.GetMessage() error = %v, want unused-message kept", err)
+	}
+	if _, _, err = ss.GetMessage(scope, "check-client-ip", tr.ID); err == nil {
+		t.Errorf("SingleSpoe.GetMessage() error = nil, want check-client-ip deleted")
+	}
+}
+
+func TestSingleSpoe_CreateSPOEMessageWithArgs(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		scope     string
+		agentName string
+		message   *models.SpoeMessage
+		args      []*models.SpoeMessageArg
+		want      models.SpoeMessageArgs
+		wantErr   bool
+	}{
+		{
+			name:      "Should fail on invalid argument name and not create message",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			message:   &models.SpoeMessage{Name: misc.StringP("check-host")},
+			args: []*models.SpoeMessageArg{
+				{Name: "host name", Expr: misc.StringP("req.hdr(host)")},
+			},
+			wantErr: true,
+		},
+		{
+			name:      "Should create message with arguments",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			message:   &models.SpoeMessage{Name: misc.StringP("check-host")},
+			args: []*models.SpoeMessageArg{
+				{Name: "host", Expr: misc.StringP("req.hdr(host)")},
+				{Expr: misc.StringP("src")},
+			},
+			want: models.SpoeMessageArgs{
+				{Name: "host", Expr: misc.StringP("req.hdr(host)")},
+				{Expr: misc.StringP("src")},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Should fail on existing message",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			message:   &models.SpoeMessage{Name: misc.StringP("check-client-ip")},
+			args: []*models.SpoeMessageArg{
+				{Expr: misc.StringP("src")},
+			},
+			wantErr: true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			message:   &models.SpoeMessage{Name: misc.StringP("check-path")},
+			args: []*models.SpoeMessageArg{
+				{Expr: misc.StringP("path")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.CreateSPOEMessageWithArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.CreateSPOEMessageWithArgs(tt.scope, tt.agentName, tt.message, tt.args, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.CreateSPOEMessageWithArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if got, _ := ss.GetVersion(""); got != v {
+					t.Errorf("SingleSpoe.GetVersion() got = %v, want %v", got, v)
+				}
+				return
+			}
+			got, err := ss.GetSPOEMessageArgs(tt.scope, tt.agentName, *tt.message.Name, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEMessageArgs() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
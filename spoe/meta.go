@@ -0,0 +1,123 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/renameio"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// metaFileSuffix ends the name of the file holding metadata of a transaction
+const metaFileSuffix = ".meta.json"
+
+// StoreTransactionMeta stores value under key in the metadata of a transaction,
+// replacing a value already stored. Metadata is kept in a {id}.meta.json file in
+// the transaction directory and is removed when the transaction is committed or
+// deleted. Returns error on fail or if value is not valid JSON.
+func (c *SingleSpoe) StoreTransactionMeta(id string, key string, value json.RawMessage) error {
+	if id == "" {
+		return conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
+	}
+	if !json.Valid(value) {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("value of %s is not valid JSON", key))
+	}
+	if !c.HasParser(id) {
+		return conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", id))
+	}
+
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	meta, err := c.readTransactionMeta(id)
+	if err != nil {
+		return err
+	}
+	meta[key] = value
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return conf.NewConfError(conf.ErrGeneralError, err.Error())
+	}
+	if err := os.MkdirAll(c.Transaction.TransactionDir, 0755); err != nil {
+		return conf.NewConfError(conf.ErrGeneralError, err.Error())
+	}
+	if err := renameio.WriteFile(c.metaFile(id), data, 0644); err != nil {
+		return conf.NewConfError(conf.ErrGeneralError, fmt.Sprintf("cannot write metadata of transaction %s: %s", id, err.Error()))
+	}
+	return nil
+}
+
+// LoadTransactionMeta returns the value stored under key in the metadata of a
+// transaction. Returns error on fail or if key is not stored.
+func (c *SingleSpoe) LoadTransactionMeta(id string, key string) (json.RawMessage, error) {
+	if id == "" {
+		return nil, conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
+	}
+	if !c.HasParser(id) {
+		return nil, conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", id))
+	}
+
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	meta, err := c.readTransactionMeta(id)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := meta[key]
+	if !ok {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("metadata %s of transaction %s does not exist", key, id))
+	}
+	return value, nil
+}
+
+func (c *SingleSpoe) metaFile(id string) string {
+	return filepath.Join(c.Transaction.TransactionDir, id+metaFileSuffix)
+}
+
+// readTransactionMeta returns metadata of a transaction, empty if none is stored
+func (c *SingleSpoe) readTransactionMeta(id string) (map[string]json.RawMessage, error) {
+	meta := map[string]json.RawMessage{}
+	data, err := ioutil.ReadFile(c.metaFile(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return nil, conf.NewConfError(conf.ErrGeneralError, fmt.Sprintf("cannot read metadata of transaction %s: %s", id, err.Error()))
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, conf.NewConfError(conf.ErrGeneralError, fmt.Sprintf("cannot read metadata of transaction %s: %s", id, err.Error()))
+	}
+	return meta, nil
+}
+
+// removeTransactionMeta removes metadata of a transaction, if any
+func (c *SingleSpoe) removeTransactionMeta(id string) {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	_ = os.Remove(c.metaFile(id))
+}
+
+// renameTransactionMeta moves metadata of a transaction to a new ID, if any
+func (c *SingleSpoe) renameTransactionMeta(oldID, newID string) {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	_ = os.Rename(c.metaFile(oldID), c.metaFile(newID))
+}
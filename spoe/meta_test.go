@@ -0,0 +1,115 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_TransactionMeta(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+
+	tests := []struct {
+		name    string
+		id      string
+		key     string
+		value   json.RawMessage
+		wantErr bool
+	}{
+		{name: "Should store object", id: tr.ID, key: "ticket", value: json.RawMessage(`{"id":42,"owner":"ops"}`), wantErr: false},
+		{name: "Should store list", id: tr.ID, key: "reviewers", value: json.RawMessage(`["a","b"]`), wantErr: false},
+		{name: "Should replace value", id: tr.ID, key: "ticket", value: json.RawMessage(`{"id":43}`), wantErr: false},
+		{name: "Should fail on invalid JSON", id: tr.ID, key: "broken", value: json.RawMessage(`{"id":`), wantErr: true},
+		{name: "Should fail on unknown transaction", id: "unknown", key: "ticket", value: json.RawMessage(`1`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ss.StoreTransactionMeta(tt.id, tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.StoreTransactionMeta() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.LoadTransactionMeta(tt.id, tt.key)
+			if err != nil {
+				t.Errorf("SingleSpoe.LoadTransactionMeta() error = %v", err)
+				return
+			}
+			if string(got) != string(tt.value) {
+				t.Errorf("SingleSpoe.LoadTransactionMeta() got = %s, want %s", got, tt.value)
+			}
+		})
+	}
+
+	if _, err := ss.LoadTransactionMeta(tr.ID, "broken"); err == nil {
+		t.Errorf("SingleSpoe.LoadTransactionMeta() error = nil, want error for missing key")
+	}
+
+	// metadata is read from disk by a new client
+	restarted, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := restarted.LoadTransactionMeta(tr.ID, "reviewers")
+	if err != nil {
+		t.Fatalf("SingleSpoe.LoadTransactionMeta() after restart error = %v", err)
+	}
+	if string(got) != `["a","b"]` {
+		t.Errorf("SingleSpoe.LoadTransactionMeta() after restart got = %s, want %s", got, `["a","b"]`)
+	}
+
+	if err := ss.Transaction.DeleteTransaction(tr.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+	if _, err := os.Stat(ss.metaFile(tr.ID)); !os.IsNotExist(err) {
+		t.Errorf("metadata file of deleted transaction exists, error = %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"reflect"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/common"
+	"github.com/haproxytech/config-parser/v3/types"
+)
+
+// DefaultMaxWaitingFrames is the HAProxy default for max-waiting-frames
+const DefaultMaxWaitingFrames = 20
+
+// MinimizeConfig removes all comments, except the _version line, and all directives
+// set to their HAProxy default value. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) MinimizeConfig(transactionID string, version int64) error {
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+
+	for scope := range p.Parsers {
+		if err := p.Set(scope, parser.Comments, parser.CommentsSectionName, "#", nil); err != nil {
+			return c.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+		}
+		for _, section := range sectionTypes {
+			for name, sectionParsers := range p.Parsers[scope][section] {
+				for _, directive := range sectionParsers.ParserSequence {
+					data, err := p.Get(scope, section, name, string(directive), false)
+					if err != nil {
+						continue
+					}
+					if section == parser.SPOEAgent && isDefaultAgentValue(string(directive), data) {
+						data = nil
+					} else {
+						stripComments(reflect.ValueOf(data))
+					}
+					if err := p.Set(scope, section, name, string(directive), data); err != nil {
+						return c.Transaction.HandleError(string(directive), string(section), name, t, transactionID == "", err)
+					}
+				}
+			}
+		}
+	}
+
+	return c.Transaction.SaveData(p, t, transactionID == "")
+}
+
+// isDefaultAgentValue checks if an agent directive holds the HAProxy default value
+func isDefaultAgentValue(directive string, data common.ParserData) bool {
+	switch d := data.(type) {
+	case *types.SimpleOption:
+		def, ok := agentFlagOptions[strings.TrimPrefix(directive, "option ")]
+		return ok && strings.HasPrefix(directive, "option ") && def == !d.NoOption
+	case *types.Int64C:
+		switch directive {
+		case "max-frame-size":
+			return d.Value == MaxFrameSize
+		case "max-waiting-frames":
+			return d.Value == DefaultMaxWaitingFrames
+		}
+	}
+	return false
+}
+
+// stripComments clears the inline Comment field of parser data, data is either
+// a pointer to a struct or a slice of structs
+func stripComments(v reflect.Value) {
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Ptr:
+		if !v.IsNil() {
+			stripComments(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			stripComments(v.Index(i))
+		}
+	case reflect.Struct:
+		if f := v.FieldByName("Comment"); f.IsValid() && f.CanSet() && f.Kind() == reflect.String {
+			f.SetString("")
+		}
+	}
+}
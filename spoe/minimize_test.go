@@ -0,0 +1,91 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_MinimizeConfig(t *testing.T) {
+	config := `# _version=1
+# global comment
+[ip-reputation]
+# scope comment
+spoe-agent iprep-agent
+    messages check-client-ip # inline comment
+    option pipelining
+    no option dontlog-normal
+    option continue-on-error
+    max-frame-size 16384
+    max-waiting-frames 20
+    use-backend agents
+
+spoe-message check-client-ip
+    args ip=src
+    event on-client-session if ! { src -f /etc/haproxy/whitelist.lst }
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	v, _ := ss.GetVersion("")
+	if err = ss.MinimizeConfig("", v); err != nil {
+		t.Fatalf("SingleSpoe.MinimizeConfig() error = %v", err)
+	}
+	p, err := ss.GetParser("")
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetParser() error = %v", err)
+	}
+	want := `# _version=2
+[ip-reputation]
+spoe-agent iprep-agent
+  messages check-client-ip
+  option continue-on-error
+  use-backend agents
+
+spoe-message check-client-ip
+  args ip=src
+  event on-client-session if ! { src -f /etc/haproxy/whitelist.lst }
+`
+	assert.Equal(t, want, p.String())
+
+	if err = ss.MinimizeConfig("", 1); err == nil {
+		t.Errorf("SingleSpoe.MinimizeConfig() error = %v, wantErr true", err)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+// MultiSpoe runs transactions spanning several SPOE files, each member is a
+// SingleSpoe keyed by a logical name. A MultiSpoe transaction holds one
+// transaction of every member, members are committed in order of name.
+type MultiSpoe struct {
+	mu           sync.Mutex
+	members      map[string]*SingleSpoe
+	transactions map[string]map[string]string
+}
+
+// PartialCommitError is returned by MultiSpoe.CommitTransaction when a member
+// failed to commit. Members committed before the failure are rolled back, those
+// which could not be rolled back are listed in RollbackFailed.
+type PartialCommitError struct {
+	Failed         map[string]error
+	RolledBack     []string
+	RollbackFailed map[string]error
+}
+
+// Error implementation for PartialCommitError
+func (e *PartialCommitError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for name, err := range e.Failed {
+		failed = append(failed, fmt.Sprintf("%s: %s", name, err.Error()))
+	}
+	sort.Strings(failed)
+	msg := fmt.Sprintf("commit failed for %s, rolled back %d members", strings.Join(failed, ", "), len(e.RolledBack))
+	if len(e.RollbackFailed) > 0 {
+		rollbackFailed := make([]string, 0, len(e.RollbackFailed))
+		for name, err := range e.RollbackFailed {
+			rollbackFailed = append(rollbackFailed, fmt.Sprintf("%s: %s", name, err.Error()))
+		}
+		sort.Strings(rollbackFailed)
+		msg += fmt.Sprintf(", rollback failed for %s", strings.Join(rollbackFailed, ", "))
+	}
+	return msg
+}
+
+// NewMultiSpoe returns a MultiSpoe over members keyed by logical name
+func NewMultiSpoe(members map[string]*SingleSpoe) (*MultiSpoe, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("members missing")
+	}
+	m := &MultiSpoe{
+		members:      make(map[string]*SingleSpoe, len(members)),
+		transactions: make(map[string]map[string]string),
+	}
+	for name, member := range members {
+		if member == nil {
+			return nil, fmt.Errorf("member %s not initialized", name)
+		}
+		m.members[name] = member
+	}
+	return m, nil
+}
+
+// ListManagedConfigs returns sorted logical names of all members, the names
+// GetSingleSpoe accepts, like the Spoe client does for file names
+func (m *MultiSpoe) ListManagedConfigs() []string {
+	names := make([]string, 0, len(m.members))
+	for name := range m.members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetSingleSpoe returns the SingleSpoe of a member, error if it does not exist
+func (m *MultiSpoe) GetSingleSpoe(name string) (*SingleSpoe, error) {
+	member, ok := m.members[name]
+	if !ok {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("spoe member %s does not exist", name))
+	}
+	return member, nil
+}
+
+// MemberTransaction returns the SingleSpoe of a member and the ID of its transaction
+// in MultiSpoe transaction id, to be passed to the member's read and write
+// operations. Returns error if member or transaction does not exist.
+func (m *MultiSpoe) MemberTransaction(name, id string) (*SingleSpoe, string, error) {
+	member, err := m.GetSingleSpoe(name)
+	if err != nil {
+		return nil, "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids, ok := m.transactions[id]
+	if !ok {
+		return nil, "", conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", id))
+	}
+	return member, ids[name], nil
+}
+
+// StartTransaction starts a transaction on every member at its current version.
+// Returns the ID of the MultiSpoe transaction, on fail transactions already
+// started are deleted and error is returned.
+func (m *MultiSpoe) StartTransaction() (string, error) {
+	ids := make(map[string]string, len(m.members))
+	for _, name := range m.ListManagedConfigs() {
+		tID, err := m.startMemberTransaction(name)
+		if err != nil {
+			_ = m.deleteMemberTransactions(ids)
+			return "", err
+		}
+		ids[name] = tID
+	}
+
+	id := uuid.New().String()
+	m.mu.Lock()
+	m.transactions[id] = ids
+	m.mu.Unlock()
+	return id, nil
+}
+
+func (m *MultiSpoe) startMemberTransaction(name string) (string, error) {
+	member := m.members[name]
+	v, err := member.GetVersion("")
+	if err != nil {
+		return "", err
+	}
+	t, err := member.Transaction.StartTransaction(v)
+	if err != nil {
+		return "", err
+	}
+	return t.ID, nil
+}
+
+// CommitTransaction commits the transaction of every member in order of name.
+// When a member fails to commit, members already committed are rolled back to
+// their previous configuration with the version incremented, remaining member
+// transactions are deleted and *PartialCommitError is returned.
+func (m *MultiSpoe) CommitTransaction(id string) error {
+	ids, err := m.takeTransaction(id)
+	if err != nil {
+		return err
+	}
+
+	committed := []string{}
+	previous := map[string]string{}
+	for _, name := range m.ListManagedConfigs() {
+		member := m.members[name]
+		p, err := member.GetParser("")
+		if err != nil {
+			return m.abortCommit(name, err, committed, previous, ids)
+		}
+		previous[name] = p.String()
+		if _, err := member.Transaction.CommitTransaction(ids[name]); err != nil {
+			return m.abortCommit(name, err, committed, previous, ids)
+		}
+		committed = append(committed, name)
+	}
+	return nil
+}
+
+// DeleteTransaction deletes the transaction of every member. All members are
+// tried, the first error is returned.
+func (m *MultiSpoe) DeleteTransaction(id string) error {
+	ids, err := m.takeTransaction(id)
+	if err != nil {
+		return err
+	}
+	return m.deleteMemberTransactions(ids)
+}
+
+// takeTransaction removes a MultiSpoe transaction and returns IDs of its member transactions
+func (m *MultiSpoe) takeTransaction(id string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids, ok := m.transactions[id]
+	if !ok {
+		return nil, conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", id))
+	}
+	delete(m.transactions, id)
+	return ids, nil
+}
+
+func (m *MultiSpoe) deleteMemberTransactions(ids map[string]string) error {
+	var first error
+	for name, tID := range ids {
+		if err := m.members[name].Transaction.DeleteTransaction(tID); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// abortCommit rolls back committed members and deletes transactions of members
+// not committed yet after member failed to commit with err
+func (m *MultiSpoe) abortCommit(failed string, err error, committed []string, previous map[string]string, ids map[string]string) error {
+	e := &PartialCommitError{
+		Failed:         map[string]error{failed: err},
+		RolledBack:     []string{},
+		RollbackFailed: map[string]error{},
+	}
+	for _, name := range committed {
+		if rbErr := m.members[name].rollbackCommit(previous[name]); rbErr != nil {
+			e.RollbackFailed[name] = rbErr
+			continue
+		}
+		e.RolledBack = append(e.RolledBack, name)
+	}
+	pending := map[string]string{}
+	for name, tID := range ids {
+		if !misc.StringInSlice(name, committed) {
+			pending[name] = tID
+		}
+	}
+	_ = m.deleteMemberTransactions(pending)
+	return e
+}
+
+// rollbackCommit replaces the master configuration with data, the configuration
+// before a commit, with the version incremented past the committed one. The file
+// is written and the master parser replaced holding the commit lock.
+func (c *SingleSpoe) rollbackCommit(data string) error {
+	p := &spoe.Parser{}
+	if err := p.ParseData(data); err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot parse previous configuration: %s", err.Error()))
+	}
+	err := c.Transaction.WithCommitLock(func() error {
+		v, err := c.GetVersion("")
+		if err != nil {
+			return err
+		}
+		ver, err := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
+		if err != nil {
+			return conf.NewConfError(conf.ErrCannotReadVersion, err.Error())
+		}
+		if d, ok := ver.(*types.ConfigVersion); ok {
+			d.Value = v + 1
+		}
+		if err := p.Save(c.Transaction.ConfigurationFile); err != nil {
+			return conf.NewConfError(conf.ErrErrorChangingConfig, fmt.Sprintf("cannot write %s: %s", c.Transaction.ConfigurationFile, err.Error()))
+		}
+		c.setParser(p)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.notifyVersionChange()
+	return nil
+}
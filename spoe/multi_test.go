@@ -0,0 +1,176 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestMultiSpoe_CommitTransaction(t *testing.T) {
+	tests := []struct {
+		name           string
+		advanceVersion string
+		wantErr        bool
+		wantBackend    string
+		wantVersions   map[string]int64
+	}{
+		{
+			name:         "Should commit all members",
+			wantBackend:  "spoe-agents",
+			wantVersions: map[string]int64{"a": 2, "b": 2},
+		},
+		{
+			name:           "Should roll back committed members when a member fails",
+			advanceVersion: "b",
+			wantErr:        true,
+			wantBackend:    "agents",
+			wantVersions:   map[string]int64{"a": 3, "b": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := map[string]*SingleSpoe{}
+			for _, name := range []string{"a", "b"} {
+				dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+				if err != nil {
+					t.Error(err.Error())
+				}
+				transactionDir, _, err := misc.CreateTempDir("", false)
+				if err != nil {
+					t.Error(err.Error())
+				}
+				defer func() {
+					_ = remove(configFile)
+					_ = remove(dir)
+					_ = remove(transactionDir)
+				}()
+				ss, err := newSingleSpoe(Params{
+					SpoeDir:           dir,
+					TransactionDir:    transactionDir,
+					ConfigurationFile: filepath.Join(dir, configFile),
+				})
+				if err != nil {
+					t.Fatalf("newSingleSpoe() error = %v", err)
+				}
+				members[name] = ss
+			}
+			m, err := NewMultiSpoe(members)
+			if err != nil {
+				t.Fatalf("NewMultiSpoe() error = %v", err)
+			}
+
+			id, err := m.StartTransaction()
+			if err != nil {
+				t.Fatalf("MultiSpoe.StartTransaction() error = %v", err)
+			}
+			for _, name := range m.ListManagedConfigs() {
+				ss, tID, err := m.MemberTransaction(name, id)
+				if err != nil {
+					t.Fatalf("MultiSpoe.MemberTransaction() error = %v", err)
+				}
+				if err = ss.SetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "spoe-agents", tID, 0); err != nil {
+					t.Fatalf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+				}
+			}
+			if tt.advanceVersion != "" {
+				if err = members[tt.advanceVersion].IncrementVersion(); err != nil {
+					t.Fatalf("SingleSpoe.IncrementVersion() error = %v", err)
+				}
+			}
+
+			err = m.CommitTransaction(id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MultiSpoe.CommitTransaction() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				var partial *PartialCommitError
+				if !errors.As(err, &partial) {
+					t.Fatalf("MultiSpoe.CommitTransaction() error = %v, want *PartialCommitError", err)
+				}
+				if _, ok := partial.Failed[tt.advanceVersion]; !ok || len(partial.RolledBack) != 1 {
+					t.Errorf("MultiSpoe.CommitTransaction() error = %+v, want %s failed and one member rolled back", partial, tt.advanceVersion)
+				}
+			}
+			for name, ss := range members {
+				got, err := ss.GetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "")
+				if err != nil {
+					t.Fatalf("SingleSpoe.GetSPOEAgentUseBackend() error = %v", err)
+				}
+				if got != tt.wantBackend {
+					t.Errorf("member %s use-backend got = %v, want %v", name, got, tt.wantBackend)
+				}
+				if v, _ := ss.GetVersion(""); v != tt.wantVersions[name] {
+					t.Errorf("member %s version got = %v, want %v", name, v, tt.wantVersions[name])
+				}
+				if n := len(ss.GetParserTransactions()); n != 0 {
+					t.Errorf("member %s has %d transactions, want 0", name, n)
+				}
+			}
+			if _, _, err := m.MemberTransaction("a", id); err == nil {
+				t.Errorf("MultiSpoe.MemberTransaction() error = nil, want error for finished transaction")
+			}
+		})
+	}
+}
+
+func TestMultiSpoe_DeleteTransaction(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	ss, err := newSingleSpoe(Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	})
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	m, err := NewMultiSpoe(map[string]*SingleSpoe{"a": ss})
+	if err != nil {
+		t.Fatalf("NewMultiSpoe() error = %v", err)
+	}
+	id, err := m.StartTransaction()
+	if err != nil {
+		t.Fatalf("MultiSpoe.StartTransaction() error = %v", err)
+	}
+	if err = m.DeleteTransaction(id); err != nil {
+		t.Errorf("MultiSpoe.DeleteTransaction() error = %v", err)
+	}
+	if n := len(ss.GetParserTransactions()); n != 0 {
+		t.Errorf("member has %d transactions, want 0", n)
+	}
+	if err = m.DeleteTransaction(id); err == nil {
+		t.Errorf("MultiSpoe.DeleteTransaction() error = nil, want error for unknown transaction")
+	}
+	if _, err = m.GetSingleSpoe("unknown"); err == nil {
+		t.Errorf("MultiSpoe.GetSingleSpoe() error = nil, want error")
+	}
+}
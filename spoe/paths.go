@@ -0,0 +1,180 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	spoe_types "github.com/haproxytech/config-parser/v3/spoe/types"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// RelativizePaths rewrites all absolute file paths found in the configuration
+// which are located under baseDir to paths relative to baseDir. File paths are
+// unix socket log targets, which keep a ./ prefix, and files loaded with -f in
+// ACLs and event conditions. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) RelativizePaths(baseDir string, transactionID string, version int64) error {
+	if !filepath.IsAbs(baseDir) {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("base dir %s is not an absolute path", baseDir))
+	}
+	baseDir = filepath.Clean(baseDir)
+	return c.rewritePaths(transactionID, version, func(path string) string {
+		if !filepath.IsAbs(path) {
+			return path
+		}
+		rel, err := filepath.Rel(baseDir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return path
+		}
+		return rel
+	})
+}
+
+// AbsolutizePaths is the reverse of RelativizePaths, it rewrites all relative
+// file paths found in the configuration to absolute paths located under baseDir.
+// One of version or transactionID is mandatory. Returns error on fail, nil on success.
+func (c *SingleSpoe) AbsolutizePaths(baseDir string, transactionID string, version int64) error {
+	if !filepath.IsAbs(baseDir) {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("base dir %s is not an absolute path", baseDir))
+	}
+	baseDir = filepath.Clean(baseDir)
+	return c.rewritePaths(transactionID, version, func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	})
+}
+
+func (c *SingleSpoe) rewritePaths(transactionID string, version int64, rewrite func(string) string) error {
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+
+	for scope := range p.Parsers {
+		if err := c.rewriteAgentPaths(scope, p, rewrite); err != nil {
+			return c.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+		}
+		if err := c.rewriteMessagePaths(scope, p, rewrite); err != nil {
+			return c.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+		}
+	}
+
+	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *SingleSpoe) rewriteAgentPaths(scope string, p *spoe.Parser, rewrite func(string) string) error {
+	agents, err := p.SectionsGet(scope, parser.SPOEAgent)
+	if err != nil {
+		return nil
+	}
+	for _, name := range agents {
+		data, err := p.Get(scope, parser.SPOEAgent, name, "log", false)
+		if err != nil {
+			continue
+		}
+		logs, ok := data.([]types.Log)
+		if !ok {
+			continue
+		}
+		for i := range logs {
+			logs[i].Address = rewriteLogAddress(logs[i].Address, rewrite)
+		}
+		if err := p.Set(scope, parser.SPOEAgent, name, "log", logs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *SingleSpoe) rewriteMessagePaths(scope string, p *spoe.Parser, rewrite func(string) string) error {
+	messages, err := p.SectionsGet(scope, parser.SPOEMessage)
+	if err != nil {
+		return nil
+	}
+	for _, name := range messages {
+		data, err := p.Get(scope, parser.SPOEMessage, name, "acl", false)
+		if err == nil {
+			if acls, ok := data.([]types.ACL); ok {
+				for i := range acls {
+					acls[i].Value = rewriteFileArgs(acls[i].Value, rewrite)
+				}
+				if err := p.Set(scope, parser.SPOEMessage, name, "acl", acls); err != nil {
+					return err
+				}
+			}
+		}
+
+		data, err = p.Get(scope, parser.SPOEMessage, name, "event", false)
+		if err == nil {
+			if event, ok := data.(*spoe_types.Event); ok && event.CondTest != "" {
+				event.CondTest = rewriteFileArgs(event.CondTest, rewrite)
+				if err := p.Set(scope, parser.SPOEMessage, name, "event", event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteLogAddress rewrites log target addresses which point to a unix socket,
+// either as a plain path or with the unix@ prefix. Relative socket paths keep a
+// ./ prefix, HAProxy reads a log target without a slash as a host name.
+func rewriteLogAddress(address string, rewrite func(string) string) string {
+	if strings.HasPrefix(address, "unix@") {
+		return "unix@" + socketPath(rewrite(strings.TrimPrefix(address, "unix@")))
+	}
+	if strings.ContainsAny(address, "@:") || !strings.Contains(address, "/") {
+		return address
+	}
+	return socketPath(rewrite(address))
+}
+
+// socketPath prefixes a relative path with ./ if it has none
+func socketPath(path string) string {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") {
+		return path
+	}
+	return "./" + path
+}
+
+// rewriteFileArgs rewrites all file names given with the -f flag
+func rewriteFileArgs(value string, rewrite func(string) string) string {
+	fields := strings.Fields(value)
+	changed := false
+	for i := 1; i < len(fields); i++ {
+		if fields[i-1] == "-f" {
+			fields[i] = rewrite(fields[i])
+			changed = true
+		}
+	}
+	if !changed {
+		return value
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_RelativizePaths(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	tests := []struct {
+		name         string
+		params       Params
+		scope        string
+		baseDir      string
+		wantRelative string
+		wantAbsolute string
+		wantErr      bool
+	}{
+		{
+			name: "Should rewrite the path of the event condition file",
+			params: Params{
+				SpoeDir:           dir,
+				TransactionDir:    transactionDir,
+				ConfigurationFile: filepath.Join(dir, configFile),
+			},
+			scope:        "[ip-reputation]",
+			baseDir:      "/etc/haproxy",
+			wantRelative: "! { src -f whitelist.lst }",
+			wantAbsolute: "! { src -f /etc/haproxy/whitelist.lst }",
+			wantErr:      false,
+		},
+		{
+			name: "Should fail on relative base dir",
+			params: Params{
+				SpoeDir:           dir,
+				TransactionDir:    transactionDir,
+				ConfigurationFile: filepath.Join(dir, configFile),
+			},
+			scope:   "[ip-reputation]",
+			baseDir: "etc/haproxy",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(tt.params)
+			if err != nil {
+				t.Errorf("SingleSpoe.RelativizePaths() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.RelativizePaths(tt.baseDir, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.RelativizePaths() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			_, m, err := ss.GetMessage(tt.scope, "check-client-ip", "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetMessage() error = %v", err)
+				return
+			}
+			if m.Event.CondTest != tt.wantRelative {
+				t.Errorf("SingleSpoe.RelativizePaths() got = %v, want %v", m.Event.CondTest, tt.wantRelative)
+			}
+
+			v, _ = ss.GetVersion("")
+			if err = ss.AbsolutizePaths(tt.baseDir, "", v); err != nil {
+				t.Errorf("SingleSpoe.AbsolutizePaths() error = %v", err)
+				return
+			}
+			_, m, err = ss.GetMessage(tt.scope, "check-client-ip", "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetMessage() error = %v", err)
+				return
+			}
+			if m.Event.CondTest != tt.wantAbsolute {
+				t.Errorf("SingleSpoe.AbsolutizePaths() got = %v, want %v", m.Event.CondTest, tt.wantAbsolute)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_RelativizeLogPaths(t *testing.T) {
+	config := `# _version=1
+[log-paths]
+spoe-agent logging-agent
+    messages check
+    log /etc/haproxy/log.sock local0
+    log unix@/etc/haproxy/unix.sock local1
+    log 127.0.0.1:514 local2
+
+spoe-message check
+    args ip=src
+    event on-client-session
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	logAddresses := func() []string {
+		p, err := ss.GetParser("")
+		if err != nil {
+			t.Fatalf("SingleSpoe.GetParser() error = %v", err)
+		}
+		data, err := p.Get("[log-paths]", parser.SPOEAgent, "logging-agent", "log", false)
+		if err != nil {
+			t.Fatalf("Parser.Get() error = %v", err)
+		}
+		addresses := []string{}
+		for _, l := range data.([]types.Log) {
+			addresses = append(addresses, l.Address)
+		}
+		return addresses
+	}
+
+	v, _ := ss.GetVersion("")
+	if err = ss.RelativizePaths("/etc/haproxy", "", v); err != nil {
+		t.Fatalf("SingleSpoe.RelativizePaths() error = %v", err)
+	}
+	assert.Equal(t, []string{"./log.sock", "unix@./unix.sock", "127.0.0.1:514"}, logAddresses())
+
+	v, _ = ss.GetVersion("")
+	if err = ss.AbsolutizePaths("/etc/haproxy", "", v); err != nil {
+		t.Fatalf("SingleSpoe.AbsolutizePaths() error = %v", err)
+	}
+	assert.Equal(t, []string{"/etc/haproxy/log.sock", "unix@/etc/haproxy/unix.sock", "127.0.0.1:514"}, logAddresses())
+}
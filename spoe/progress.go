@@ -0,0 +1,66 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"time"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// transactionStats are kept for each transaction, base sections and creation time
+// are only known for transactions started after Init
+type transactionStats struct {
+	started      bool
+	baseSections int
+	created      time.Time
+	operations   int
+}
+
+// TransactionProgress reports the changes staged in a transaction
+type TransactionProgress struct {
+	OperationsStaged   int       `json:"operations_staged"`
+	EstimatedSizeBytes int64     `json:"estimated_size_bytes"`
+	StartedAt          time.Time `json:"started_at"`
+}
+
+// GetTransactionProgress returns the number of changing calls made in a transaction,
+// the size of its configuration and when it was started. Transactions found on Init
+// use the modification time of their transaction file as start time and only count
+// calls made since. Returns error on fail or if transaction does not exist.
+func (c *SingleSpoe) GetTransactionProgress(id string) (*TransactionProgress, error) {
+	if id == "" {
+		return nil, conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
+	}
+	p, err := c.GetParser(id)
+	if err != nil {
+		return nil, err
+	}
+	started, err := c.transactionCreatedAt(id)
+	if err != nil {
+		return nil, err
+	}
+	progress := &TransactionProgress{
+		EstimatedSizeBytes: int64(len(p.String())),
+		StartedAt:          started,
+	}
+	c.parsersMu.RLock()
+	if stats, ok := c.transactionStats[id]; ok {
+		progress.OperationsStaged = stats.operations
+	}
+	c.parsersMu.RUnlock()
+	return progress, nil
+}
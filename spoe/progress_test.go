@@ -0,0 +1,97 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_GetTransactionProgress(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	before := time.Now()
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+
+	got, err := ss.GetTransactionProgress(tr.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetTransactionProgress() error = %v", err)
+	}
+	if got.OperationsStaged != 0 {
+		t.Errorf("OperationsStaged got = %v, want 0", got.OperationsStaged)
+	}
+	if got.StartedAt.Before(before) || got.StartedAt.After(time.Now()) {
+		t.Errorf("StartedAt got = %v, want after %v", got.StartedAt, before)
+	}
+	size := got.EstimatedSizeBytes
+
+	scope, agent := "[ip-reputation]", "iprep-agent"
+	if _, err = ss.SetSPOEAgentIdleTimeout(scope, agent, time.Minute, tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentIdleTimeout() error = %v", err)
+	}
+	if err = ss.SetSPOEAgentVarPrefix(scope, agent, "a-much-longer-prefix", tr.ID, 0); err == nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentVarPrefix() error = nil, want error")
+	}
+	if err = ss.SetSPOEAgentVarPrefix(scope, agent, "a_much_longer_prefix", tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentVarPrefix() error = %v", err)
+	}
+	got, err = ss.GetTransactionProgress(tr.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetTransactionProgress() error = %v", err)
+	}
+	if got.OperationsStaged != 2 {
+		t.Errorf("OperationsStaged got = %v, want 2", got.OperationsStaged)
+	}
+	if got.EstimatedSizeBytes <= size {
+		t.Errorf("EstimatedSizeBytes got = %v, want more than %v", got.EstimatedSizeBytes, size)
+	}
+
+	if _, err = ss.GetTransactionProgress("unknown"); err == nil {
+		t.Errorf("SingleSpoe.GetTransactionProgress() error = nil, want error")
+	}
+	if _, err = ss.GetTransactionProgress(""); err == nil {
+		t.Errorf("SingleSpoe.GetTransactionProgress() error = nil, want error")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/renameio"
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// PartialSaveError is returned by SaveAll when not all files were saved
+type PartialSaveError struct {
+	Saved  []string
+	Failed map[string]error
+}
+
+// Error implementation for PartialSaveError
+func (e *PartialSaveError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for f, err := range e.Failed {
+		failed = append(failed, fmt.Sprintf("%s: %s", f, err.Error()))
+	}
+	sort.Strings(failed)
+	return fmt.Sprintf("saved %d files, failed to save %d files: %s", len(e.Saved), len(e.Failed), strings.Join(failed, ", "))
+}
+
+// SaveAll writes the configuration file and files of all loaded transactions.
+// All files are first written to temporary files in the same directories and
+// only when all of them are written they are renamed over the originals.
+// Returns *PartialSaveError if any file was not saved.
+func (c *SingleSpoe) SaveAll() error {
+	master, err := c.GetParser("")
+	if err != nil {
+		return err
+	}
+	if err = c.signParser(master, false); err != nil {
+		return err
+	}
+	files := map[string]string{c.Transaction.ConfigurationFile: master.String()}
+	if c.Transaction.PersistentTransactions {
+		c.parsersMu.Lock()
+		for tID, p := range c.parsers {
+			if f, err := c.Transaction.GetTransactionFile(tID); err == nil {
+				if err = c.signParser(p, true); err != nil {
+					c.parsersMu.Unlock()
+					return err
+				}
+				files[f] = p.String()
+				// the HMAC is only kept in the file
+				if err = c.signParser(p, false); err != nil {
+					c.parsersMu.Unlock()
+					return err
+				}
+			}
+		}
+		c.parsersMu.Unlock()
+	}
+	names := make([]string, 0, len(files))
+	for f := range files {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	saveErr := &PartialSaveError{Saved: []string{}, Failed: make(map[string]error)}
+	pending := make(map[string]*renameio.PendingFile, len(names))
+	defer func() {
+		for _, t := range pending {
+			_ = t.Cleanup()
+		}
+	}()
+	for _, f := range names {
+		t, err := renameio.TempFile("", f)
+		if err != nil {
+			saveErr.Failed[f] = err
+			continue
+		}
+		pending[f] = t
+		if err = t.Chmod(0644); err != nil {
+			saveErr.Failed[f] = err
+			continue
+		}
+		if _, err = t.WriteString(files[f]); err != nil {
+			saveErr.Failed[f] = err
+		}
+	}
+	// do not replace any file if one of them could not be written
+	if len(saveErr.Failed) > 0 {
+		for _, f := range names {
+			if _, ok := saveErr.Failed[f]; !ok {
+				saveErr.Failed[f] = fmt.Errorf("not saved, other files failed")
+			}
+		}
+		return saveErr
+	}
+
+	for _, f := range names {
+		if err := pending[f].CloseAtomicallyReplace(); err != nil {
+			saveErr.Failed[f] = err
+			continue
+		}
+		saveErr.Saved = append(saveErr.Saved, f)
+	}
+	if len(saveErr.Failed) > 0 {
+		return saveErr
+	}
+	return nil
+}
+
+// ReplaceSPOEFile replaces the configuration file with the configuration in srcPath.
+// Holding the commit lock, the current configuration is backed up like on commit
+// when BackupsNumber is set, the new configuration is written atomically with the
+// version incremented and the master parser is reloaded. Returns ErrValidationError
+// if srcPath can not be parsed or fails Validate, and error on fail.
+func (c *SingleSpoe) ReplaceSPOEFile(srcPath string) error {
+	data, err := readConfigFile(srcPath)
+	if err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s: %s", srcPath, err.Error()))
+	}
+	p := &spoe.Parser{}
+	if err = p.ParseData(data); err != nil {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("cannot parse %s: %s", srcPath, err.Error()))
+	}
+	if errs := c.validateParser(p); len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("invalid configuration %s: %s", srcPath, strings.Join(msgs, ", ")))
+	}
+
+	var old *spoe.Parser
+	err = c.Transaction.WithCommitLock(func() error {
+		v, err := c.GetVersion("")
+		if err != nil {
+			return err
+		}
+		ver, err := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
+		if err != nil {
+			return conf.NewConfError(conf.ErrCannotReadVersion, err.Error())
+		}
+		if d, ok := ver.(*types.ConfigVersion); ok {
+			d.Value = v + 1
+		}
+
+		configFile := c.Transaction.ConfigurationFile
+		info, err := os.Stat(configFile)
+		if err != nil {
+			return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s: %s", configFile, err.Error()))
+		}
+		c.Transaction.BackupConfiguration(v)
+		if err = renameio.WriteFile(configFile, []byte(p.String()), info.Mode()); err != nil {
+			return conf.NewConfError(conf.ErrErrorChangingConfig, fmt.Sprintf("cannot write %s: %s", configFile, err.Error()))
+		}
+		old = c.setParser(p)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.notifyVersionChange()
+	c.notifyCommit("", old, p)
+	return nil
+}
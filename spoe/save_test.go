@@ -0,0 +1,202 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_SaveAll(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	configPath := filepath.Join(dir, configFile)
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: configPath,
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	v, _ := ss.GetVersion("")
+	tr, err := ss.Transaction.StartTransaction(v)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	tFile, _ := ss.Transaction.GetTransactionFile(tr.ID)
+
+	// change parsers in memory only
+	_ = ss.Parser.Set("[ip-reputation]", parser.SPOEAgent, "iprep-agent", "use-backend", &types.StringC{Value: "master-backend"})
+	p, _ := ss.GetParser(tr.ID)
+	_ = p.Set("[ip-reputation]", parser.SPOEAgent, "iprep-agent", "use-backend", &types.StringC{Value: "transaction-backend"})
+
+	if err = ss.SaveAll(); err != nil {
+		t.Fatalf("SingleSpoe.SaveAll() error = %v", err)
+	}
+	for file, want := range map[string]string{configPath: "master-backend", tFile: "transaction-backend"} {
+		data, _ := ioutil.ReadFile(file)
+		if !strings.Contains(string(data), "use-backend "+want) {
+			t.Errorf("SingleSpoe.SaveAll() %s does not contain use-backend %s", file, want)
+		}
+	}
+
+	// configuration file can not be written, transaction file must stay unchanged
+	_ = p.Set("[ip-reputation]", parser.SPOEAgent, "iprep-agent", "use-backend", &types.StringC{Value: "other-backend"})
+	ss.Transaction.ConfigurationFile = filepath.Join(dir, "missing", configFile)
+	err = ss.SaveAll()
+	saveErr, ok := err.(*PartialSaveError)
+	if !ok {
+		t.Fatalf("SingleSpoe.SaveAll() error = %v, want *PartialSaveError", err)
+	}
+	if len(saveErr.Saved) != 0 || len(saveErr.Failed) != 2 {
+		t.Errorf("SingleSpoe.SaveAll() saved = %v, failed = %v", saveErr.Saved, saveErr.Failed)
+	}
+	data, _ := ioutil.ReadFile(tFile)
+	if strings.Contains(string(data), "other-backend") {
+		t.Errorf("SingleSpoe.SaveAll() %s changed on failure", tFile)
+	}
+}
+
+func TestSingleSpoe_ReplaceSPOEFile(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	configPath := filepath.Join(dir, configFile)
+	srcPath := filepath.Join(dir, "replacement.conf")
+	invalidPath := filepath.Join(dir, "invalid.conf")
+	defer func() {
+		_ = remove(configPath + ".1")
+		_ = remove(configPath + ".2")
+		_ = remove(srcPath)
+		_ = remove(invalidPath)
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: configPath,
+		BackupsNumber:     1,
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	commits := ss.ListenForTransactionCommits(ctx, nil)
+
+	replacement := `# _version=7
+[new-scope]
+spoe-agent new-agent
+    use-backend new-agents
+`
+	if err = ioutil.WriteFile(srcPath, []byte(replacement), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+	invalid := `[new-scope]
+spoe-agent new-agent
+    messages unknown-message
+`
+	if err = ioutil.WriteFile(invalidPath, []byte(invalid), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+
+	if err = ss.ReplaceSPOEFile(invalidPath); err == nil {
+		t.Errorf("SingleSpoe.ReplaceSPOEFile() error = nil, want error")
+	}
+	if err = ss.ReplaceSPOEFile(filepath.Join(dir, "missing.conf")); err == nil {
+		t.Errorf("SingleSpoe.ReplaceSPOEFile() error = nil, want error")
+	}
+	if v, _ := ss.GetVersion(""); v != 1 {
+		t.Errorf("SingleSpoe.GetVersion() got = %v, want 1", v)
+	}
+
+	if err = ss.ReplaceSPOEFile(srcPath); err != nil {
+		t.Fatalf("SingleSpoe.ReplaceSPOEFile() error = %v", err)
+	}
+	if v, _ := ss.GetVersion(""); v != 2 {
+		t.Errorf("SingleSpoe.GetVersion() got = %v, want 2", v)
+	}
+	_, scopes, err := ss.GetScopes("")
+	if err != nil || len(scopes) != 1 || scopes[0] != "[new-scope]" {
+		t.Errorf("SingleSpoe.GetScopes() got = %v, error = %v, want [[new-scope]]", scopes, err)
+	}
+	select {
+	case info := <-commits:
+		if info.TransactionID != "" || info.Version != 2 {
+			t.Errorf("SingleSpoe.ListenForTransactionCommits() got = %+v, want version 2", info)
+		}
+	default:
+		t.Error("SingleSpoe.ListenForTransactionCommits() no commit reported")
+	}
+	backup, err := ioutil.ReadFile(configPath + ".1")
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(backup), "spoe-agent iprep-agent") {
+		t.Errorf("backup got = %q, want configuration of version 1", string(backup))
+	}
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(content), "# _version=2\n") || !strings.Contains(string(content), "spoe-agent new-agent") {
+		t.Errorf("configuration file got = %q", string(content))
+	}
+
+	// only BackupsNumber backups are kept
+	if err = ss.ReplaceSPOEFile(srcPath); err != nil {
+		t.Fatalf("SingleSpoe.ReplaceSPOEFile() error = %v", err)
+	}
+	if _, err = os.Stat(configPath + ".1"); !os.IsNotExist(err) {
+		t.Errorf("os.Stat() error = %v, want backup of version 1 removed", err)
+	}
+	if _, err = os.Stat(configPath + ".2"); err != nil {
+		t.Errorf("os.Stat() error = %v, want backup of version 2", err)
+	}
+}
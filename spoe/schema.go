@@ -0,0 +1,149 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+
+	parser "github.com/haproxytech/config-parser/v3"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// bundledSchema lists SPOE directives known to the SPOE client, keyed the same way
+// as the config parser keys them
+const bundledSchema = `{
+  "haproxy_version": "2.2",
+  "directives": [
+    {"name": "groups", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "log", "sections": ["spoe-agent"], "type": "log"},
+    {"name": "maxconnrate", "sections": ["spoe-agent"], "type": "number"},
+    {"name": "maxerrrate", "sections": ["spoe-agent"], "type": "number"},
+    {"name": "max-frame-size", "sections": ["spoe-agent"], "type": "number"},
+    {"name": "max-waiting-frames", "sections": ["spoe-agent"], "type": "number"},
+    {"name": "messages", "sections": ["spoe-agent", "spoe-group"], "type": "string"},
+    {"name": "option async", "sections": ["spoe-agent"], "type": "option"},
+    {"name": "option continue-on-error", "sections": ["spoe-agent"], "type": "option"},
+    {"name": "option dontlog-normal", "sections": ["spoe-agent"], "type": "option"},
+    {"name": "option force-set-var", "sections": ["spoe-agent"], "type": "option"},
+    {"name": "option pipelining", "sections": ["spoe-agent"], "type": "option"},
+    {"name": "option send-frag-payload", "sections": ["spoe-agent"], "type": "option"},
+    {"name": "option set-on-error", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "option set-process-time", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "option set-total-time", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "option var-prefix", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "register-var-names", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "timeout hello", "sections": ["spoe-agent"], "type": "time"},
+    {"name": "timeout idle", "sections": ["spoe-agent"], "type": "time"},
+    {"name": "timeout processing", "sections": ["spoe-agent"], "type": "time"},
+    {"name": "use-backend", "sections": ["spoe-agent"], "type": "string"},
+    {"name": "acl", "sections": ["spoe-message"], "type": "acl"},
+    {"name": "args", "sections": ["spoe-message"], "type": "string"},
+    {"name": "event", "sections": ["spoe-message"], "type": "event"}
+  ]
+}`
+
+// DirectiveSchema describes one SPOE directive
+type DirectiveSchema struct {
+	Name     string   `json:"name"`
+	Sections []string `json:"sections"`
+	Type     string   `json:"type"`
+}
+
+// SchemaRegistry holds SPOE directives known for a HAProxy version
+type SchemaRegistry struct {
+	HAProxyVersion string            `json:"haproxy_version"`
+	Directives     []DirectiveSchema `json:"directives"`
+	index          map[parser.Section]map[string]DirectiveSchema
+}
+
+// NewSchemaRegistry returns a SchemaRegistry populated from the bundled schema
+func NewSchemaRegistry() (*SchemaRegistry, error) {
+	return LoadSchemaRegistry([]byte(bundledSchema))
+}
+
+// LoadSchemaRegistry returns a SchemaRegistry populated from JSON data in the
+// format of the bundled schema
+func LoadSchemaRegistry(data []byte) (*SchemaRegistry, error) {
+	r := &SchemaRegistry{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("cannot parse SPOE schema: %s", err.Error())
+	}
+	r.index = make(map[parser.Section]map[string]DirectiveSchema)
+	for _, d := range r.Directives {
+		for _, s := range d.Sections {
+			section := parser.Section(s)
+			if _, ok := r.index[section]; !ok {
+				r.index[section] = make(map[string]DirectiveSchema)
+			}
+			r.index[section][d.Name] = d
+		}
+	}
+	return r, nil
+}
+
+// Lookup returns the schema of a directive in section
+func (r *SchemaRegistry) Lookup(section parser.Section, directive string) (DirectiveSchema, bool) {
+	d, ok := r.index[section][directive]
+	return d, ok
+}
+
+// IsKnown checks whether directive is known in any section type
+func (r *SchemaRegistry) IsKnown(directive string) bool {
+	for _, section := range sectionTypes {
+		if _, ok := r.Lookup(section, directive); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDirective returns an ErrUnknownDirective error if directive is not known
+// in any section type. It is meant as a warning, directives added in newer
+// HAProxy versions are not known to the registry.
+func (r *SchemaRegistry) CheckDirective(directive string) error {
+	if r.IsKnown(directive) {
+		return nil
+	}
+	return conf.NewConfError(conf.ErrUnknownDirective, fmt.Sprintf("unknown SPOE directive %s for HAProxy %s", directive, r.HAProxyVersion))
+}
+
+var haproxyVersionRe = regexp.MustCompile(`HA-?Proxy version (\d+\.\d+)`) //nolint:gochecknoglobals
+
+// CheckHAProxyVersion compares the registry version with the version reported by
+// the haproxy binary. Returns ErrVersionMismatch if major and minor differ.
+func (r *SchemaRegistry) CheckHAProxyVersion(haproxy string) error {
+	// #nosec G204
+	out, err := exec.Command(haproxy, "-v").Output()
+	if err != nil {
+		return conf.NewConfError(conf.ErrCannotFindHAProxy, fmt.Sprintf("cannot run %s: %s", haproxy, err.Error()))
+	}
+	return r.checkVersionOutput(string(out))
+}
+
+func (r *SchemaRegistry) checkVersionOutput(out string) error {
+	m := haproxyVersionRe.FindStringSubmatch(out)
+	if m == nil {
+		return conf.NewConfError(conf.ErrCannotReadVersion, "cannot read HAProxy version")
+	}
+	if m[1] != r.HAProxyVersion {
+		return conf.NewConfError(conf.ErrVersionMismatch, fmt.Sprintf("SPOE schema is for HAProxy %s, running HAProxy %s", r.HAProxyVersion, m[1]))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v3"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSchemaRegistry_CheckDirective(t *testing.T) {
+	r, err := NewSchemaRegistry()
+	if err != nil {
+		t.Fatalf("NewSchemaRegistry() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		directive string
+		wantErr   bool
+	}{
+		{name: "Should know agent directive", directive: "use-backend", wantErr: false},
+		{name: "Should know agent option", directive: "option var-prefix", wantErr: false},
+		{name: "Should know message directive", directive: "event", wantErr: false},
+		{name: "Should warn on unknown directive", directive: "nb-workers", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.CheckDirective(tt.directive)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SchemaRegistry.CheckDirective() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if e, ok := err.(*conf.ConfError); !ok || e.Code() != conf.ErrUnknownDirective {
+					t.Errorf("SchemaRegistry.CheckDirective() error = %v, want ErrUnknownDirective", err)
+				}
+			}
+		})
+	}
+	if d, ok := r.Lookup(parser.SPOEGroup, "messages"); !ok || d.Type != "string" {
+		t.Errorf("SchemaRegistry.Lookup() = %v, %v", d, ok)
+	}
+	if _, ok := r.Lookup(parser.SPOEGroup, "use-backend"); ok {
+		t.Errorf("SchemaRegistry.Lookup() found use-backend in %s", parser.SPOEGroup)
+	}
+}
+
+func TestSchemaRegistry_checkVersionOutput(t *testing.T) {
+	r, err := NewSchemaRegistry()
+	if err != nil {
+		t.Fatalf("NewSchemaRegistry() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		out     string
+		wantErr bool
+	}{
+		{name: "Should match same version", out: "HA-Proxy version 2.2.4-de45672 2020/09/30 - https://haproxy.org/", wantErr: false},
+		{name: "Should match new banner", out: "HAProxy version 2.2.17-dd94a25 2021/09/07 - https://haproxy.org/", wantErr: false},
+		{name: "Should fail on other version", out: "HA-Proxy version 2.4.0 2021/05/14 - https://haproxy.org/", wantErr: true},
+		{name: "Should fail on unknown output", out: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.checkVersionOutput(tt.out); (err != nil) != tt.wantErr {
+				t.Errorf("SchemaRegistry.checkVersionOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_ReplaceAllOccurrencesUnknownDirective(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	v, _ := ss.GetVersion("")
+	got, warnings, err := ss.ReplaceAllOccurrences("[ip-reputation]", "nb-workers", "1", "2", "", v)
+	if err != nil {
+		t.Errorf("SingleSpoe.ReplaceAllOccurrences() error = %v", err)
+	}
+	if got != 0 {
+		t.Errorf("SingleSpoe.ReplaceAllOccurrences() got = %v, want 0", got)
+	}
+	if len(warnings) != 1 || warnings[0].Name != "nb-workers" {
+		t.Errorf("SingleSpoe.ReplaceAllOccurrences() warnings = %v, want unknown directive nb-workers", warnings)
+	}
+}
@@ -17,8 +17,12 @@ package spoe
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/go-openapi/strfmt"
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
 
 	conf "github.com/haproxytech/client-native/v2/configuration"
 	"github.com/haproxytech/client-native/v2/models"
@@ -48,6 +52,46 @@ func (c *SingleSpoe) GetScopes(transactionID string) (int64, models.SpoeScopes,
 	return v, scopes, nil
 }
 
+// ListSPOEScopes returns sorted names of all scopes, empty slice when there are none.
+// Returns error on fail.
+func (c *SingleSpoe) ListSPOEScopes(transactionID string) ([]string, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{}
+	for name := range p.Parsers {
+		if p.IsScope(name) {
+			scopes = append(scopes, name)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+// GetAllSectionTypes returns section types with at least one section in scope, in
+// the order they are written to the configuration file.
+// Returns error on fail or if scope does not exist.
+func (c *SingleSpoe) GetAllSectionTypes(scope string, transactionID string) ([]parser.Section, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.Parsers[scope]; !ok || !p.IsScope(scope) {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("scope %s does not exist", scope))
+	}
+
+	found := []parser.Section{}
+	for _, section := range sectionTypes {
+		names, err := p.SectionsGet(scope, section)
+		if err == nil && len(names) > 0 {
+			found = append(found, section)
+		}
+	}
+	return found, nil
+}
+
 // GetScope returns configuration version and a requested scope
 // Returns error on fail or if scope does not exist.
 func (c *SingleSpoe) GetScope(name, transactionID string) (int64, *models.SpoeScope, error) {
@@ -122,3 +166,96 @@ func (c *SingleSpoe) CreateScope(data *models.SpoeScope, transactionID string, v
 
 	return nil
 }
+
+// CopyScope copies all agents, groups and messages of srcScope to destScope,
+// destScope is created if it does not exist. If destScope already has sections
+// with the same names nothing is copied and an error listing all conflicts is
+// returned. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (c *SingleSpoe) CopyScope(srcScope, destScope string, transactionID string, version int64) error {
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := p.Parsers[srcScope]; !ok || !p.IsScope(srcScope) {
+		e := conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("scope %s does not exist", srcScope))
+		return c.Transaction.HandleError(srcScope, "", "", t, transactionID == "", e)
+	}
+	if srcScope == destScope {
+		e := conf.NewConfError(conf.ErrValidationError, "source and destination scope are the same")
+		return c.Transaction.HandleError(srcScope, "", "", t, transactionID == "", e)
+	}
+
+	// parse a copy of the source scope renamed to destination scope, so
+	// copied sections do not share data with the source
+	cp := &spoe.Parser{}
+	if err := cp.ParseData(renameScope(scopeText(p, srcScope), srcScope, destScope)); err != nil {
+		return c.Transaction.HandleError(destScope, "", "", t, transactionID == "", err)
+	}
+	if err := c.insertScopeSections(p, cp, destScope); err != nil {
+		return c.Transaction.HandleError(destScope, "", "", t, transactionID == "", err)
+	}
+
+	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+// insertScopeSections adds all sections of scope in src to scope in p, scope is
+// created in p if it does not exist. If p already has sections with the same names
+// nothing is added and an error listing all conflicts is returned.
+func (c *SingleSpoe) insertScopeSections(p, src *spoe.Parser, scope string) error {
+	conflicts := []error{}
+	for _, section := range sectionTypes {
+		names, err := src.SectionsGet(scope, section)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if c.checkSectionExists(scope, section, name, p) {
+				conflicts = append(conflicts, conf.NewConfError(conf.ErrObjectAlreadyExists, fmt.Sprintf("%s %s already exists in %s", section, name, scope)))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return conf.CompositeTransactionError(conflicts...)
+	}
+
+	if _, ok := p.Parsers[scope]; !ok {
+		if err := p.ScopeCreate(scope); err != nil {
+			return err
+		}
+	}
+	for _, section := range sectionTypes {
+		for name, data := range src.Parsers[scope][section] {
+			p.Parsers[scope][section][name] = data
+		}
+	}
+	return nil
+}
+
+// scopeText returns the serialized configuration of one scope, including its header
+func scopeText(p *spoe.Parser, scope string) string {
+	var b strings.Builder
+	inScope := false
+	for _, line := range strings.Split(p.String(), "\n") {
+		if isScopeHeader(line) {
+			inScope = line == scope
+		}
+		if inScope {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func renameScope(text, oldScope, newScope string) string {
+	return strings.Replace(text, oldScope, newScope, 1)
+}
+
+func isScopeHeader(line string) bool {
+	return strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")
+}
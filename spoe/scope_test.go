@@ -20,6 +20,8 @@ import (
 	"reflect"
 	"testing"
 
+	parser "github.com/haproxytech/config-parser/v3"
+
 	"github.com/haproxytech/client-native/v2/misc"
 	"github.com/haproxytech/client-native/v2/models"
 )
@@ -233,3 +235,223 @@ func TestSingleSpoe_GetScope(t *testing.T) {
 		})
 	}
 }
+
+func TestSingleSpoe_ListSPOEScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Should list scopes",
+			config:  basicConfig + "\n[other]\nspoe-group other-group\n    messages other\n",
+			want:    []string{"[ip-reputation]", "[other]"},
+			wantErr: false,
+		},
+		{
+			name:    "Should return empty list without scopes",
+			config:  "# _version=1\n",
+			want:    []string{},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, configFile, err := misc.CreateTempDir(tt.config, true)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			transactionDir, _, err := misc.CreateTempDir("", false)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			defer func() {
+				_ = remove(configFile)
+				_ = remove(dir)
+				_ = remove(transactionDir)
+			}()
+			params := Params{
+				SpoeDir:           dir,
+				TransactionDir:    transactionDir,
+				ConfigurationFile: filepath.Join(dir, configFile),
+			}
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.ListSPOEScopes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			got, err := ss.ListSPOEScopes("")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.ListSPOEScopes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SingleSpoe.ListSPOEScopes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_GetAllSectionTypes(t *testing.T) {
+	config := basicConfig + "\n[other]\nspoe-group other-group\n    messages other\n\n[empty]\n"
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name    string
+		scope   string
+		want    []parser.Section
+		wantErr bool
+	}{
+		{
+			name:    "Should return all section types",
+			scope:   "[ip-reputation]",
+			want:    []parser.Section{parser.SPOEAgent, parser.SPOEGroup, parser.SPOEMessage},
+			wantErr: false,
+		},
+		{
+			name:    "Should return populated section types",
+			scope:   "[other]",
+			want:    []parser.Section{parser.SPOEGroup},
+			wantErr: false,
+		},
+		{
+			name:    "Should return empty list for empty scope",
+			scope:   "[empty]",
+			want:    []parser.Section{},
+			wantErr: false,
+		},
+		{
+			name:    "Should fail on unknown scope",
+			scope:   "[unknown]",
+			wantErr: true,
+		},
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ss.GetAllSectionTypes(tt.scope, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.GetAllSectionTypes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SingleSpoe.GetAllSectionTypes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_CopyScope(t *testing.T) {
+	config := basicConfig + `
+[conflict]
+spoe-group mygroup
+    messages other
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		srcScope  string
+		destScope string
+		wantErr   bool
+	}{
+		{
+			name:      "Should copy scope to new scope",
+			srcScope:  "[ip-reputation]",
+			destScope: "[copy]",
+			wantErr:   false,
+		},
+		{
+			name:      "Should fail on conflicting sections",
+			srcScope:  "[ip-reputation]",
+			destScope: "[conflict]",
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown scope",
+			srcScope:  "[unknown]",
+			destScope: "[copy2]",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.CopyScope() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			err = ss.CopyScope(tt.srcScope, tt.destScope, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.CopyScope() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			_, src, err := ss.GetAgent(tt.srcScope, "iprep-agent", "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetAgent() error = %v", err)
+				return
+			}
+			_, dest, err := ss.GetAgent(tt.destScope, "iprep-agent", "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetAgent() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(src, dest) {
+				t.Errorf("SingleSpoe.CopyScope() got = %v, want %v", dest, src)
+			}
+			if _, _, err = ss.GetGroup(tt.destScope, "mygroup", ""); err != nil {
+				t.Errorf("SingleSpoe.GetGroup() error = %v", err)
+			}
+			// copies must not share data with the source
+			v, _ = ss.GetVersion("")
+			if err = ss.SetSPOEAgentUseBackend(tt.destScope, "iprep-agent", "copy-backend", "", v); err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+				return
+			}
+			backend, _ := ss.GetSPOEAgentUseBackend(tt.srcScope, "iprep-agent", "")
+			if backend != "agents" {
+				t.Errorf("SingleSpoe.CopyScope() source use-backend = %v, want agents", backend)
+			}
+		})
+	}
+}
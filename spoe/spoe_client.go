@@ -23,6 +23,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 
 	"github.com/google/renameio"
 
@@ -38,6 +39,13 @@ type Spoe interface {
 	Get(name string) (string, error)
 }
 
+// ManagedConfigLister lists the SPOE files with a loaded client, the Spoe returned
+// by NewSpoe implements it. It is kept apart from Spoe so existing implementations
+// of Spoe are not broken.
+type ManagedConfigLister interface {
+	ListManagedConfigs() []string
+}
+
 type spoeclient struct {
 	clients    map[string]*SingleSpoe
 	initParams Params
@@ -64,6 +72,12 @@ func NewSpoe(params Params) (Spoe, error) {
 		UseValidation:          params.UseValidation,
 		SpoeDir:                params.SpoeDir,
 		SkipFailedTransactions: params.PersistentTransactions,
+		LazyLoadTransactions:   params.LazyLoadTransactions,
+		MaxMessageArgs:         params.MaxMessageArgs,
+		EncryptionKey:          params.EncryptionKey,
+		AuditLog:               params.AuditLog,
+		DrainTimeout:           params.DrainTimeout,
+		MaxVersionDrift:        params.MaxVersionDrift,
 	}
 	c.clients = make(map[string]*SingleSpoe)
 	for _, f := range files {
@@ -85,6 +99,18 @@ func (c *spoeclient) GetSingleSpoe(name string) (*SingleSpoe, error) {
 	return nil, fmt.Errorf("client %s not configured", name)
 }
 
+// ListManagedConfigs returns sorted file names of all SPOE clients, the names
+// GetSingleSpoe accepts. Unlike GetAll, files added to the SPOE directory by other
+// means are not listed.
+func (c *spoeclient) ListManagedConfigs() []string {
+	names := make([]string, 0, len(c.clients))
+	for name := range c.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GetAll returns array of configured spoe files or nil if any not found.
 func (c *spoeclient) GetAll() ([]string, error) {
 	files, err := c.getSpoeFiles(c.initParams.SpoeDir)
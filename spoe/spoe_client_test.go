@@ -21,7 +21,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/haproxytech/client-native/v2/misc"
 )
 
 func remove(file string) error {
@@ -80,3 +83,51 @@ func Test_spoeclient_Create(t *testing.T) {
 		})
 	}
 }
+
+func Test_spoeclient_ListManagedConfigs(t *testing.T) {
+	spoeDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		_ = remove(filepath.Join(spoeDir, "b.cfg"))
+		_ = remove(filepath.Join(spoeDir, "a.cfg"))
+		_ = remove(spoeDir)
+		_ = remove(transactionDir)
+	}()
+	c := spoeclient{
+		clients: make(map[string]*SingleSpoe),
+		initParams: Params{
+			SpoeDir:        spoeDir,
+			TransactionDir: transactionDir,
+		},
+	}
+	if got := c.ListManagedConfigs(); len(got) != 0 {
+		t.Errorf("spoeclient.ListManagedConfigs() = %v, want []", got)
+	}
+	for _, name := range []string{"b.cfg", "a.cfg"} {
+		if _, err = c.Create(name, ioutil.NopCloser(bytes.NewReader([]byte(basicConfig)))); err != nil {
+			t.Fatalf("spoeclient.Create() error = %v", err)
+		}
+	}
+	want := []string{"a.cfg", "b.cfg"}
+	if got := c.ListManagedConfigs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("spoeclient.ListManagedConfigs() = %v, want %v", got, want)
+	}
+
+	s, err := NewSpoe(c.initParams)
+	if err != nil {
+		t.Fatalf("NewSpoe() error = %v", err)
+	}
+	lister, ok := s.(ManagedConfigLister)
+	if !ok {
+		t.Fatalf("NewSpoe() does not implement ManagedConfigLister")
+	}
+	if got := lister.ListManagedConfigs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ManagedConfigLister.ListManagedConfigs() = %v, want %v", got, want)
+	}
+}
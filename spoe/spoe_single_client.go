@@ -16,9 +16,19 @@
 package spoe
 
 import (
+	"context"
+	"errors"
 	"fmt"
-
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
 	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/common"
 	"github.com/haproxytech/config-parser/v3/spoe"
 	"github.com/haproxytech/config-parser/v3/types"
 
@@ -33,10 +43,39 @@ import (
 // parsers map contains a SPOE parser for each transaction, which loads data from
 // transaction files on StartTransaction, and deletes on CommitTransaction
 // We save data to file on every change for persistence
+// When transactions are lazy loaded, transactionFileIndex holds the files of
+// transactions found on Init which are not yet loaded in the parsers map
+// transactionStats holds what is known about each transaction parser
+// parsersMu guards Parser, parsers, transactionFileIndex and transactionStats, so
+// transactions can be started, committed and deleted from concurrent goroutines
+// Schema lists known SPOE directives, used to warn about unknown ones when validating
+// versionSubs are channels of SubscribeVersionChanges subscribers
+// commitSubs are channels of ListenForTransactionCommits listeners with their filters
+// maxMessageArgs limits the number of arguments of a message, 0 means no limit
+// encryptionKey signs transaction files with an HMAC when set
+// auditLog logs transactions rejected by the integrity check on Init
+// ctx is canceled by Close, which waits for background goroutines counted in workers
 type SingleSpoe struct {
-	parsers     map[string]*spoe.Parser
-	Parser      *spoe.Parser
-	Transaction *conf.Transaction
+	parsersMu            sync.RWMutex
+	parsers              map[string]*spoe.Parser
+	transactionFileIndex map[string]string
+	transactionStats     map[string]*transactionStats
+	lazyLoadTransactions bool
+	Parser               *spoe.Parser
+	Transaction          *conf.Transaction
+	Schema               *SchemaRegistry
+	versionSubsMu        sync.Mutex
+	versionSubs          map[chan int64]struct{}
+	commitSubsMu         sync.Mutex
+	commitSubs           map[chan CommitInfo]func(CommitInfo) bool
+	maxMessageArgs       int
+	encryptionKey        string
+	auditLog             func(format string, args ...interface{})
+	metaMu               sync.Mutex
+	ctx                  context.Context
+	cancel               context.CancelFunc
+	workers              sync.WaitGroup
+	drainTimeout         time.Duration
 }
 
 type Params struct {
@@ -47,6 +86,12 @@ type Params struct {
 	TransactionDir         string
 	BackupsNumber          int
 	ConfigurationFile      string
+	LazyLoadTransactions   bool
+	MaxMessageArgs         int
+	EncryptionKey          string
+	AuditLog               func(format string, args ...interface{})
+	DrainTimeout           time.Duration
+	MaxVersionDrift        int64
 }
 
 // newSingleSpoe returns Spoe with default options
@@ -76,9 +121,26 @@ func newSingleSpoe(params Params) (*SingleSpoe, error) {
 		UseValidation:          useValidation,
 		PersistentTransactions: persistentTransactions,
 		SkipFailedTransactions: skipFailedTransactions,
+		MaxVersionDrift:        params.MaxVersionDrift,
+	}
+
+	schema, err := NewSchemaRegistry()
+	if err != nil {
+		return nil, err
 	}
+	ss.Schema = schema
 
 	ss.parsers = make(map[string]*spoe.Parser)
+	ss.transactionFileIndex = make(map[string]string)
+	ss.transactionStats = make(map[string]*transactionStats)
+	ss.lazyLoadTransactions = params.LazyLoadTransactions
+	ss.maxMessageArgs = params.MaxMessageArgs
+	ss.encryptionKey = params.EncryptionKey
+	ss.auditLog = params.AuditLog
+	if ss.auditLog == nil {
+		ss.auditLog = func(string, ...interface{}) {}
+	}
+	ss.newCloseContext(params.DrainTimeout)
 	if err := ss.InitTransactionParsers(); err != nil {
 		return nil, err
 	}
@@ -91,42 +153,126 @@ func newSingleSpoe(params Params) (*SingleSpoe, error) {
 	return ss, nil
 }
 
+// NewSingleSpoeFromParser returns Spoe with default options using an already loaded
+// parser as the master parser, the parser is written to params.ConfigurationFile
+func NewSingleSpoeFromParser(p *spoe.Parser, params Params) (*SingleSpoe, error) {
+	if p == nil {
+		return nil, fmt.Errorf("parser missing")
+	}
+	if params.ConfigurationFile == "" {
+		return nil, fmt.Errorf("configuration file missing")
+	}
+	if err := p.Save(params.ConfigurationFile); err != nil {
+		return nil, conf.NewConfError(conf.ErrErrorChangingConfig, fmt.Sprintf("cannot write %s: %s", params.ConfigurationFile, err.Error()))
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		return nil, err
+	}
+	ss.Parser = p
+	return ss, nil
+}
+
 func (c *SingleSpoe) CheckTransactionOrVersion(transactionID string, version int64) (string, error) {
 	return c.Transaction.CheckTransactionOrVersion(transactionID, version)
 }
 
 // HasParser checks whether transaction exists in parser
 func (c *SingleSpoe) HasParser(transactionID string) bool {
-	_, ok := c.parsers[transactionID]
+	c.parsersMu.RLock()
+	defer c.parsersMu.RUnlock()
+	return c.hasParser(transactionID)
+}
+
+func (c *SingleSpoe) hasParser(transactionID string) bool {
+	if _, ok := c.parsers[transactionID]; ok {
+		return true
+	}
+	_, ok := c.transactionFileIndex[transactionID]
 	return ok
 }
 
-// GetParserTransactions returns parser transactions
+// GetParserTransactions returns parser transactions, the version of transactions
+// which are not loaded yet is read from the header of their file
 func (c *SingleSpoe) GetParserTransactions() models.Transactions {
 	transactions := models.Transactions{}
-	for tID := range c.parsers {
-		v, err := c.GetVersion(tID)
-		if err == nil {
-			t := &models.Transaction{
-				ID:      tID,
-				Status:  "in_progress",
-				Version: v,
-			}
-			transactions = append(transactions, t)
+	c.parsersMu.RLock()
+	versions := make(map[string]int64, len(c.parsers))
+	for tID, p := range c.parsers {
+		if v, err := parserVersion(p); err == nil {
+			versions[tID] = v
 		}
 	}
+	files := make(map[string]string, len(c.transactionFileIndex))
+	for tID, tFile := range c.transactionFileIndex {
+		files[tID] = tFile
+	}
+	c.parsersMu.RUnlock()
+	for tID, tFile := range files {
+		if v, err := readFileHeaderVersion(tFile); err == nil {
+			versions[tID] = v
+		}
+	}
+	for tID, v := range versions {
+		t := &models.Transaction{
+			ID:      tID,
+			Status:  "in_progress",
+			Version: v,
+		}
+		if expiresAt, ok := c.Transaction.GetTransactionExpiry(tID); ok {
+			t.ExpiresAt = strfmt.DateTime(expiresAt)
+		}
+		transactions = append(transactions, t)
+	}
 	return transactions
 }
 
+// SetTransactionTTL deletes transactions started or written after the call once d
+// passes without a write to them, d of 0 or less disables expiry
+func (c *SingleSpoe) SetTransactionTTL(d time.Duration) {
+	c.Transaction.SetTransactionTTL(d)
+}
+
+// StopReaper disables transaction expiry, transactions in progress are kept
+func (c *SingleSpoe) StopReaper() {
+	c.Transaction.StopReaper()
+}
+
 // GetParser returns a parser for given transactionID, if transactionID is "", it returns "master" parser
 func (c *SingleSpoe) GetParser(transactionID string) (*spoe.Parser, error) {
+	c.parsersMu.RLock()
 	if transactionID == "" {
+		defer c.parsersMu.RUnlock()
 		return c.Parser, nil
 	}
 	p, ok := c.parsers[transactionID]
+	c.parsersMu.RUnlock()
+	if ok {
+		return p, nil
+	}
+	// lazy loading adds the parser to the map
+	c.parsersMu.Lock()
+	defer c.parsersMu.Unlock()
+	return c.getParser(transactionID)
+}
+
+// getParser returns the parser of a transaction, loading it if it is only indexed,
+// parsersMu must be held
+func (c *SingleSpoe) getParser(transactionID string) (*spoe.Parser, error) {
+	p, ok := c.parsers[transactionID]
+	if ok {
+		return p, nil
+	}
+	tFile, ok := c.transactionFileIndex[transactionID]
 	if !ok {
 		return nil, conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", transactionID))
 	}
+	p = &spoe.Parser{}
+	if err := c.loadTransactionParser(p, tFile); err != nil {
+		return nil, err
+	}
+	c.parsers[transactionID] = p
+	delete(c.transactionFileIndex, transactionID)
 	return p, nil
 }
 
@@ -135,11 +281,6 @@ func (c *SingleSpoe) AddParser(transactionID string) error {
 	if transactionID == "" {
 		return conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
 	}
-	_, ok := c.parsers[transactionID]
-	if ok {
-		return conf.NewConfError(conf.ErrTransactionAlreadyExists, fmt.Sprintf("transaction %s already exists", transactionID))
-	}
-
 	p := &spoe.Parser{}
 	tFile := ""
 	var err error
@@ -151,10 +292,29 @@ func (c *SingleSpoe) AddParser(transactionID string) error {
 	} else {
 		tFile = c.Transaction.ConfigurationFile
 	}
-	if err := p.LoadData(tFile); err != nil {
-		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", tFile))
+	if err := c.loadTransactionParser(p, tFile); err != nil {
+		return err
+	}
+	// the check and the insert are done under one lock, the parser is loaded before
+	c.parsersMu.Lock()
+	if c.hasParser(transactionID) {
+		c.parsersMu.Unlock()
+		return conf.NewConfError(conf.ErrTransactionAlreadyExists, fmt.Sprintf("transaction %s already exists", transactionID))
 	}
 	c.parsers[transactionID] = p
+	// the master parser is not loaded yet for transactions found on Init
+	if c.Parser != nil {
+		c.transactionStats[transactionID] = &transactionStats{
+			started:      true,
+			baseSections: countSections(c.Parser),
+			created:      time.Now(),
+		}
+	}
+	c.parsersMu.Unlock()
+	// sign the transaction file copied from the configuration file
+	if c.encryptionKey != "" && c.Transaction.PersistentTransactions {
+		return c.Save(tFile, transactionID)
+	}
 	return nil
 }
 
@@ -163,11 +323,15 @@ func (c *SingleSpoe) DeleteParser(transactionID string) error {
 	if transactionID == "" {
 		return conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
 	}
-	_, ok := c.parsers[transactionID]
-	if !ok {
+	c.parsersMu.Lock()
+	defer c.parsersMu.Unlock()
+	if !c.hasParser(transactionID) {
 		return conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", transactionID))
 	}
 	delete(c.parsers, transactionID)
+	delete(c.transactionFileIndex, transactionID)
+	delete(c.transactionStats, transactionID)
+	c.removeTransactionMeta(transactionID)
 	return nil
 }
 
@@ -176,16 +340,159 @@ func (c *SingleSpoe) CommitParser(transactionID string) error {
 	if transactionID == "" {
 		return conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
 	}
-	p, ok := c.parsers[transactionID]
-	if !ok {
-		return conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", transactionID))
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return err
 	}
+	c.parsersMu.Lock()
+	old := c.Parser
 	c.Parser = p
 	delete(c.parsers, transactionID)
+	delete(c.transactionStats, transactionID)
+	c.parsersMu.Unlock()
+	c.removeTransactionMeta(transactionID)
+	c.notifyVersionChange()
+	c.notifyCommit(transactionID, old, p)
 	return nil
 }
 
-// InitTransactionParsers checks transactions and initializes parsers map with transactions in_progress
+// setParser replaces the master parser, returns the replaced one
+func (c *SingleSpoe) setParser(p *spoe.Parser) *spoe.Parser {
+	c.parsersMu.Lock()
+	defer c.parsersMu.Unlock()
+	old := c.Parser
+	c.Parser = p
+	return old
+}
+
+// RenewTransactionID issues a new ID for an existing transaction, renaming the
+// transaction file when transactions are persistent. Returns the new ID and error on fail.
+func (c *SingleSpoe) RenewTransactionID(oldID string) (string, error) {
+	if oldID == "" {
+		return "", conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
+	}
+	c.parsersMu.Lock()
+	defer c.parsersMu.Unlock()
+	if !c.hasParser(oldID) {
+		return "", conf.NewConfError(conf.ErrTransactionDoesNotExist, fmt.Sprintf("transaction %s does not exist", oldID))
+	}
+
+	newID := uuid.New().String()
+	tFile := ""
+	if c.Transaction.PersistentTransactions {
+		oldFile, err := c.Transaction.GetTransactionFile(oldID)
+		if err != nil {
+			return "", err
+		}
+		tFile = filepath.Join(filepath.Dir(oldFile), filepath.Base(filepath.Clean(c.Transaction.ConfigurationFile))+"."+newID)
+		if err := os.Rename(oldFile, tFile); err != nil {
+			return "", conf.NewConfError(conf.ErrGeneralError, fmt.Sprintf("cannot rename transaction file %s: %s", oldFile, err.Error()))
+		}
+	}
+
+	if p, ok := c.parsers[oldID]; ok {
+		c.parsers[newID] = p
+		delete(c.parsers, oldID)
+	}
+	if _, ok := c.transactionFileIndex[oldID]; ok {
+		c.transactionFileIndex[newID] = tFile
+		delete(c.transactionFileIndex, oldID)
+	}
+	if stats, ok := c.transactionStats[oldID]; ok {
+		c.transactionStats[newID] = stats
+		delete(c.transactionStats, oldID)
+	}
+	c.renameTransactionMeta(oldID, newID)
+	return newID, nil
+}
+
+// ForEachTransaction calls visit with the parser of each transaction in progress, in
+// order of transaction ID, until visit returns an error which is then returned.
+// Transactions can not be added or removed during iteration, visit must not
+// call methods of c that access transaction parsers.
+func (c *SingleSpoe) ForEachTransaction(visit func(id string, p *spoe.Parser) error) error {
+	c.parsersMu.Lock()
+	defer c.parsersMu.Unlock()
+	ids := make([]string, 0, len(c.parsers)+len(c.transactionFileIndex))
+	for id := range c.parsers {
+		ids = append(ids, id)
+	}
+	for id := range c.transactionFileIndex {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p, err := c.getParser(id)
+		if err != nil {
+			return err
+		}
+		if err := visit(id, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quiesceInterval is how often WaitForQuiesce checks for transactions in progress
+var quiesceInterval = 100 * time.Millisecond //nolint:gochecknoglobals
+
+// WaitForQuiesce blocks until there are no transactions in progress, all of them
+// committed or deleted, or until ctx is done. Returns ctx error if ctx is done first.
+func (c *SingleSpoe) WaitForQuiesce(ctx context.Context) error {
+	ticker := time.NewTicker(quiesceInterval)
+	defer ticker.Stop()
+	for {
+		c.parsersMu.RLock()
+		pending := len(c.parsers) + len(c.transactionFileIndex)
+		c.parsersMu.RUnlock()
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetTransactionSize returns the number of sections in the transaction parser minus
+// the number of sections in the master parser when the transaction was started.
+// For transactions loaded on Init the current master parser is the base.
+// Returns error on fail or if transaction does not exist.
+func (c *SingleSpoe) GetTransactionSize(id string) (int, error) {
+	if id == "" {
+		return 0, conf.NewConfError(conf.ErrValidationError, "not a valid transaction")
+	}
+	p, err := c.GetParser(id)
+	if err != nil {
+		return 0, err
+	}
+	c.parsersMu.RLock()
+	base := countSections(c.Parser)
+	if stats, ok := c.transactionStats[id]; ok && stats.started {
+		base = stats.baseSections
+	}
+	c.parsersMu.RUnlock()
+	return countSections(p) - base, nil
+}
+
+// countSections returns the number of sections in all scopes of p
+func countSections(p *spoe.Parser) int {
+	count := 0
+	for scope := range p.Parsers {
+		for _, section := range sectionTypes {
+			names, err := p.SectionsGet(scope, section)
+			if err == nil {
+				count += len(names)
+			}
+		}
+	}
+	return count
+}
+
+// InitTransactionParsers checks transactions and initializes parsers map with transactions in_progress,
+// with lazy loading only the transaction files are indexed and parsed on first use
 func (c *SingleSpoe) InitTransactionParsers() error {
 	transactions, err := c.Transaction.GetTransactions("in_progress")
 	if err != nil {
@@ -193,6 +500,23 @@ func (c *SingleSpoe) InitTransactionParsers() error {
 	}
 
 	for _, t := range *transactions {
+		if err := c.VerifyTransactionIntegrity(t.ID); err != nil {
+			c.auditLog("SPOE transaction %s of %s rejected: %s", t.ID, c.Transaction.ConfigurationFile, err.Error())
+			if c.Transaction.SkipFailedTransactions {
+				continue
+			}
+			return err
+		}
+		if c.lazyLoadTransactions {
+			tFile, err := c.Transaction.GetTransactionFile(t.ID)
+			if err != nil {
+				return err
+			}
+			c.parsersMu.Lock()
+			c.transactionFileIndex[t.ID] = tFile
+			c.parsersMu.Unlock()
+			continue
+		}
 		if err := c.AddParser(t.ID); err != nil {
 			continue
 		}
@@ -204,28 +528,30 @@ func (c *SingleSpoe) InitTransactionParsers() error {
 		if err != nil {
 			return err
 		}
-		if err := p.LoadData(tFile); err != nil {
-			return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", tFile))
+		if err := c.loadTransactionParser(p, tFile); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 func (c *SingleSpoe) IncrementVersion() error {
-	data, _ := c.Parser.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
+	p, err := c.GetParser("")
+	if err != nil {
+		return err
+	}
+	data, _ := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
 	ver, _ := data.(*types.ConfigVersion)
 	ver.Value++
 
-	if err := c.Parser.Save(c.Transaction.ConfigurationFile); err != nil {
+	if err := p.Save(c.Transaction.ConfigurationFile); err != nil {
 		return conf.NewConfError(conf.ErrCannotSetVersion, fmt.Sprintf("cannot set version: %s", err.Error()))
 	}
+	c.notifyVersionChange()
 	return nil
 }
 
 func (c *SingleSpoe) IncrementTransactionVersion(transactionID string) error {
-	if transactionID == "" {
-		return c.incrementTransactionVersion(c.Parser)
-	}
 	p, err := c.GetParser(transactionID)
 	if err != nil {
 		return err
@@ -243,23 +569,45 @@ func (c *SingleSpoe) incrementTransactionVersion(p *spoe.Parser) error {
 	return nil
 }
 
+// LoadData loads configuration file to the master parser, gzip compressed backups are
+// decompressed on the fly
 func (c *SingleSpoe) LoadData(filename string) error {
-	err := c.Parser.LoadData(filename)
+	data, err := readConfigFile(filename)
 	if err != nil {
 		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", filename))
 	}
+	p, err := c.GetParser("")
+	if err != nil {
+		return err
+	}
+	if err := p.ParseData(data); err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", filename))
+	}
 	return nil
 }
 
 func (c *SingleSpoe) Save(transactionFile, transactionID string) error {
-	if transactionID == "" {
-		return c.Parser.Save(transactionFile)
-	}
 	p, err := c.GetParser(transactionID)
 	if err != nil {
 		return err
 	}
-	return p.Save(transactionFile)
+	if transactionID == "" {
+		if err := c.signParser(p, false); err != nil {
+			return err
+		}
+		return p.Save(transactionFile)
+	}
+	commit := filepath.Clean(transactionFile) == filepath.Clean(c.Transaction.ConfigurationFile)
+	// only transaction files are signed, not the configuration file on commit
+	if err := c.signParser(p, !commit); err != nil {
+		return err
+	}
+	err = p.Save(transactionFile)
+	// the HMAC is only kept in the file
+	if unsignErr := c.signParser(p, false); err == nil {
+		err = unsignErr
+	}
+	return err
 }
 
 func (c *SingleSpoe) GetFailedParserTransactionVersion(transactionID string) (int64, error) {
@@ -287,13 +635,18 @@ func (c *SingleSpoe) getVersion(transactionID string) (int64, error) {
 	if err != nil {
 		return 0, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version: %s", err.Error()))
 	}
+	return parserVersion(p)
+}
+
+// parserVersion returns the version of p
+func parserVersion(p *spoe.Parser) (int64, error) {
 	data, err := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", true)
 	if err != nil {
 		return 0, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version: %s", err.Error()))
 	}
 	ver, ok := data.(*types.ConfigVersion)
 	if !ok {
-		return 0, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version: %s", err.Error()))
+		return 0, conf.NewConfError(conf.ErrCannotReadVersion, "cannot read version")
 	}
 	return ver.Value, nil
 }
@@ -306,11 +659,11 @@ func (c *SingleSpoe) deleteSection(scope string, section parser.Section, name st
 
 	if !c.checkSectionExists(scope, section, name, p) {
 		e := conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", section, name))
-		return c.Transaction.HandleError(name, "", "", t, transactionID == "", e)
+		return c.handleError(scope, section, name, t, transactionID == "", e)
 	}
 
 	if err := p.SectionsDelete(scope, section, name); err != nil {
-		return c.Transaction.HandleError(name, "", "", t, transactionID == "", err)
+		return c.handleError(scope, section, name, t, transactionID == "", err)
 	}
 
 	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
@@ -320,6 +673,56 @@ func (c *SingleSpoe) deleteSection(scope string, section parser.Section, name st
 	return nil
 }
 
+// handleError converts err like Transaction.HandleError does and wraps configuration
+// errors in SPOEError with scope and section type, deleting implicit transaction
+func (c *SingleSpoe) handleError(scope string, section parser.Section, id, transactionID string, implicit bool, err error) error {
+	e := c.Transaction.HandleError(id, "", "", transactionID, implicit, err)
+	var confErr *conf.ConfError
+	if errors.As(e, &confErr) && !errors.As(e, new(*SPOEError)) {
+		return &SPOEError{ConfError: confErr, Scope: scope, SectionType: string(section)}
+	}
+	return e
+}
+
+// editSection runs edit on the parser of the given transaction, or of an implicit
+// one if transactionID is empty, and saves the changes if section exists and edit succeeded
+func (c *SingleSpoe) editSection(scope string, section parser.Section, name string, transactionID string, version int64, edit func(p *spoe.Parser) error) error {
+	p, t, err := c.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+
+	if !c.checkSectionExists(scope, section, name, p) {
+		e := conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", section, name))
+		return c.handleError(scope, section, name, t, transactionID == "", e)
+	}
+
+	if err := edit(p); err != nil {
+		return c.handleError(scope, section, name, t, transactionID == "", err)
+	}
+
+	if err := c.Transaction.SaveData(p, t, transactionID == ""); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getSectionDirective returns the data of one directive in a section, creating an
+// empty one if directive is not set
+func (c *SingleSpoe) getSectionDirective(scope string, section parser.Section, name, attribute, transactionID string) (common.ParserData, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.checkSectionExists(scope, section, name, p) {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("%s %s does not exist", section, name))
+	}
+
+	return p.Get(scope, section, name, attribute, true)
+}
+
 func (c *SingleSpoe) checkSectionExists(scope string, section parser.Section, sectionName string, p *spoe.Parser) bool {
 	sections, err := p.SectionsGet(scope, section)
 	if err != nil {
@@ -332,6 +735,14 @@ func (c *SingleSpoe) checkSectionExists(scope string, section parser.Section, se
 	return false
 }
 
+// checkAgentExists checks that agent which is the parent of a message or group exists
+func (c *SingleSpoe) checkAgentExists(scope, agentName string, p *spoe.Parser) error {
+	if !c.checkSectionExists(scope, parser.SPOEAgent, agentName, p) {
+		return conf.NewConfError(conf.ErrParentDoesNotExist, fmt.Sprintf("%s %s does not exist", parser.SPOEAgent, agentName))
+	}
+	return nil
+}
+
 func (c *SingleSpoe) loadDataForChange(transactionID string, version int64) (*spoe.Parser, string, error) {
 	t, err := c.CheckTransactionOrVersion(transactionID, version)
 	if err != nil {
@@ -349,5 +760,11 @@ func (c *SingleSpoe) loadDataForChange(transactionID string, version int64) (*sp
 		}
 		return nil, "", err
 	}
+	c.parsersMu.Lock()
+	if _, ok := c.transactionStats[t]; !ok {
+		c.transactionStats[t] = &transactionStats{}
+	}
+	c.transactionStats[t].operations++
+	c.parsersMu.Unlock()
 	return p, t, nil
 }
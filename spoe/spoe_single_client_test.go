@@ -0,0 +1,763 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/config-parser/v3/spoe"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+func TestSingleSpoe_LazyLoadTransactions(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	tests := []struct {
+		name       string
+		lazyLoad   bool
+		wantLoaded int
+	}{
+		{
+			name:       "Should load transactions on init",
+			lazyLoad:   false,
+			wantLoaded: 1,
+		},
+		{
+			name:       "Should load transactions on first use",
+			lazyLoad:   true,
+			wantLoaded: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := Params{
+				SpoeDir:           dir,
+				TransactionDir:    transactionDir,
+				ConfigurationFile: filepath.Join(dir, configFile),
+			}
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("newSingleSpoe() error = %v", err)
+				return
+			}
+			tr, err := ss.Transaction.StartTransaction(1)
+			if err != nil {
+				t.Errorf("Transaction.StartTransaction() error = %v", err)
+				return
+			}
+			defer func() {
+				_ = ss.Transaction.DeleteTransaction(tr.ID)
+			}()
+
+			params.LazyLoadTransactions = tt.lazyLoad
+			lss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("newSingleSpoe() error = %v", err)
+				return
+			}
+			if len(lss.parsers) != tt.wantLoaded {
+				t.Errorf("SingleSpoe.parsers got = %v, want %v", len(lss.parsers), tt.wantLoaded)
+			}
+			transactions := lss.GetParserTransactions()
+			if len(transactions) != 1 || transactions[0].ID != tr.ID || transactions[0].Version != 1 {
+				t.Errorf("SingleSpoe.GetParserTransactions() got = %v, want %s at version 1", transactions, tr.ID)
+			}
+			// listing does not load transactions
+			if len(lss.parsers) != tt.wantLoaded {
+				t.Errorf("SingleSpoe.parsers got = %v, want %v", len(lss.parsers), tt.wantLoaded)
+			}
+			if !lss.HasParser(tr.ID) {
+				t.Errorf("SingleSpoe.HasParser() got = false, want true")
+			}
+			if _, err := lss.GetParser(tr.ID); err != nil {
+				t.Errorf("SingleSpoe.GetParser() error = %v", err)
+			}
+			if len(lss.parsers) != 1 {
+				t.Errorf("SingleSpoe.parsers got = %v, want 1", len(lss.parsers))
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_RenewTransactionID(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	newID, err := ss.RenewTransactionID(tr.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.RenewTransactionID() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(newID)
+	}()
+	if newID == tr.ID {
+		t.Errorf("SingleSpoe.RenewTransactionID() got = %v, want new ID", newID)
+	}
+	if ss.HasParser(tr.ID) {
+		t.Errorf("SingleSpoe.HasParser(%s) got = true, want false", tr.ID)
+	}
+	if _, err := ss.Transaction.GetTransactionFile(tr.ID); err == nil {
+		t.Errorf("Transaction.GetTransactionFile(%s) error = nil, want error", tr.ID)
+	}
+	if _, err := ss.Transaction.GetTransactionFile(newID); err != nil {
+		t.Errorf("Transaction.GetTransactionFile(%s) error = %v", newID, err)
+	}
+	if _, err := ss.Transaction.CommitTransaction(newID); err != nil {
+		t.Errorf("Transaction.CommitTransaction(%s) error = %v", newID, err)
+	}
+
+	if _, err := ss.RenewTransactionID("unknown"); err == nil {
+		t.Errorf("SingleSpoe.RenewTransactionID() error = nil, want error")
+	}
+}
+
+func TestNewSingleSpoeFromParser(t *testing.T) {
+	dir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, "spoe.cfg"),
+	}
+	p := &spoe.Parser{}
+	if err = p.ParseData(basicConfig); err != nil {
+		t.Fatalf("Parser.ParseData() error = %v", err)
+	}
+	ss, err := NewSingleSpoeFromParser(p, params)
+	if err != nil {
+		t.Fatalf("NewSingleSpoeFromParser() error = %v", err)
+	}
+	if ss.Parser != p {
+		t.Errorf("SingleSpoe.Parser is not the given parser")
+	}
+	data, err := ioutil.ReadFile(params.ConfigurationFile)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	if string(data) != p.String() {
+		t.Errorf("configuration file got = %v, want %v", string(data), p.String())
+	}
+	if _, _, err = ss.GetAgent("[ip-reputation]", "iprep-agent", ""); err != nil {
+		t.Errorf("SingleSpoe.GetAgent() error = %v", err)
+	}
+
+	if _, err = NewSingleSpoeFromParser(nil, params); err == nil {
+		t.Errorf("NewSingleSpoeFromParser() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_GetTransactionSize(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+
+	if got, err := ss.GetTransactionSize(tr.ID); err != nil || got != 0 {
+		t.Errorf("SingleSpoe.GetTransactionSize() got = %v, error = %v, want 0", got, err)
+	}
+	p, _ := ss.GetParser(tr.ID)
+	_ = p.SectionsCreate("[ip-reputation]", "spoe-group", "other-group")
+	_ = p.SectionsCreate("[ip-reputation]", "spoe-message", "other-message")
+	if got, err := ss.GetTransactionSize(tr.ID); err != nil || got != 2 {
+		t.Errorf("SingleSpoe.GetTransactionSize() got = %v, error = %v, want 2", got, err)
+	}
+	if _, err := ss.GetTransactionSize("unknown"); err == nil {
+		t.Errorf("SingleSpoe.GetTransactionSize() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_ForEachTransaction(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	ids := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		tr, err := ss.Transaction.StartTransaction(1)
+		if err != nil {
+			t.Fatalf("Transaction.StartTransaction() error = %v", err)
+		}
+		ids[tr.ID] = true
+		defer func() {
+			_ = ss.Transaction.DeleteTransaction(tr.ID)
+		}()
+	}
+
+	visited := map[string]bool{}
+	err = ss.ForEachTransaction(func(id string, p *spoe.Parser) error {
+		if p == nil {
+			return errors.New("nil parser")
+		}
+		visited[id] = true
+		return nil
+	})
+	if err != nil {
+		t.Errorf("SingleSpoe.ForEachTransaction() error = %v", err)
+	}
+	if !reflect.DeepEqual(visited, ids) {
+		t.Errorf("SingleSpoe.ForEachTransaction() visited = %v, want %v", visited, ids)
+	}
+
+	count := 0
+	stop := errors.New("stop")
+	err = ss.ForEachTransaction(func(id string, p *spoe.Parser) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("SingleSpoe.ForEachTransaction() error = %v, visits = %d, want stop after 1", err, count)
+	}
+}
+
+func TestSingleSpoe_WaitForQuiesce(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	if err = ss.WaitForQuiesce(context.Background()); err != nil {
+		t.Errorf("SingleSpoe.WaitForQuiesce() error = %v", err)
+	}
+
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = ss.WaitForQuiesce(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SingleSpoe.WaitForQuiesce() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- ss.WaitForQuiesce(context.Background())
+	}()
+	if _, err = ss.Transaction.CommitTransaction(tr.ID); err != nil {
+		t.Fatalf("Transaction.CommitTransaction() error = %v", err)
+	}
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("SingleSpoe.WaitForQuiesce() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("SingleSpoe.WaitForQuiesce() did not return after commit")
+	}
+}
+
+func TestSingleSpoe_ConcurrentTransactions(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+	}()
+	tests := []struct {
+		name       string
+		persistent bool
+		workers    int
+		commit     bool
+	}{
+		{name: "Should start and delete persistent transactions", persistent: true, workers: 16, commit: false},
+		{name: "Should start and delete in memory transactions", persistent: false, workers: 16, commit: false},
+		{name: "Should start and commit persistent transactions", persistent: true, workers: 16, commit: true},
+		{name: "Should start and commit in memory transactions", persistent: false, workers: 16, commit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactionDir, _, err := misc.CreateTempDir("", false)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			defer func() {
+				_ = remove(transactionDir)
+			}()
+			persistent := tt.persistent
+			ss, err := newSingleSpoe(Params{
+				SpoeDir:                dir,
+				TransactionDir:         transactionDir,
+				ConfigurationFile:      filepath.Join(dir, configFile),
+				PersistentTransactions: &persistent,
+			})
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			v, err := ss.GetVersion("")
+			if err != nil {
+				t.Fatalf("SingleSpoe.GetVersion() error = %v", err)
+			}
+
+			var wg sync.WaitGroup
+			committed := int32(0)
+			for i := 0; i < tt.workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					tr, err := ss.Transaction.StartTransaction(v)
+					if err != nil {
+						// an other worker committed first
+						return
+					}
+					_ = ss.GetParserTransactions()
+					if !ss.HasParser(tr.ID) {
+						t.Errorf("SingleSpoe.HasParser(%s) = false, want true", tr.ID)
+					}
+					if _, err := ss.GetParser(tr.ID); err != nil {
+						t.Errorf("SingleSpoe.GetParser(%s) error = %v", tr.ID, err)
+					}
+					if tt.commit {
+						if _, err := ss.Transaction.CommitTransaction(tr.ID); err == nil {
+							atomic.AddInt32(&committed, 1)
+						}
+						return
+					}
+					if err := ss.Transaction.DeleteTransaction(tr.ID); err != nil {
+						t.Errorf("DeleteTransaction(%s) error = %v", tr.ID, err)
+					}
+				}()
+			}
+
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("concurrent transactions did not finish, deadlock")
+			}
+
+			if tt.commit && committed != 1 {
+				t.Errorf("committed %d transactions, want 1", committed)
+			}
+			if got := len(ss.GetParserTransactions()); !tt.commit && got != 0 {
+				t.Errorf("SingleSpoe.GetParserTransactions() got %d transactions, want 0", got)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_ReplaceDuringCommit(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	configPath := filepath.Join(dir, configFile)
+	srcPath := filepath.Join(dir, "replacement.conf")
+	defer func() {
+		_ = os.RemoveAll(dir)
+		_ = remove(transactionDir)
+	}()
+	replacement := `[ip-reputation]
+spoe-agent iprep-agent
+    use-backend agents
+`
+	if err = ioutil.WriteFile(srcPath, []byte(replacement), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+	ss, err := newSingleSpoe(Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: configPath,
+	})
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if err := ss.ReplaceSPOEFile(srcPath); err != nil {
+				t.Errorf("SingleSpoe.ReplaceSPOEFile() error = %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			v, _ := ss.GetVersion("")
+			tr, err := ss.Transaction.StartTransaction(v)
+			if err != nil {
+				// replaced after the version was read
+				continue
+			}
+			_, _ = ss.Transaction.CommitTransaction(tr.ID)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("replace and commit did not finish, deadlock")
+	}
+
+	v, err := ss.GetVersion("")
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetVersion() error = %v", err)
+	}
+	fileVersion, err := ReadSPOEFileVersion(configPath)
+	if err != nil {
+		t.Fatalf("ReadSPOEFileVersion() error = %v", err)
+	}
+	if fileVersion != v {
+		t.Errorf("ReadSPOEFileVersion() got = %v, want %v", fileVersion, v)
+	}
+}
+
+func TestSingleSpoe_SetTransactionTTL(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		write     bool
+		stop      bool
+		wantAlive bool
+	}{
+		{name: "Should delete expired transaction", ttl: 100 * time.Millisecond, wantAlive: false},
+		{name: "Should keep transaction written to before expiry", ttl: 200 * time.Millisecond, write: true, wantAlive: true},
+		{name: "Should keep transaction when reaper is stopped", ttl: 100 * time.Millisecond, stop: true, wantAlive: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			defer ss.StopReaper()
+			ss.SetTransactionTTL(tt.ttl)
+			v, _ := ss.GetVersion("")
+			tr, err := ss.Transaction.StartTransaction(v)
+			if err != nil {
+				t.Fatalf("StartTransaction() error = %v", err)
+			}
+			defer func() {
+				_ = ss.Transaction.DeleteTransaction(tr.ID)
+			}()
+			if time.Time(tr.ExpiresAt).IsZero() {
+				t.Errorf("StartTransaction() ExpiresAt is not set")
+			}
+			transactions := ss.GetParserTransactions()
+			if len(transactions) != 1 || time.Time(transactions[0].ExpiresAt).IsZero() {
+				t.Errorf("SingleSpoe.GetParserTransactions() got = %v, want one transaction with ExpiresAt", transactions)
+			}
+
+			if tt.stop {
+				ss.StopReaper()
+			}
+			if tt.write {
+				time.Sleep(tt.ttl * 3 / 4)
+				if err := ss.SetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "spoe-agents", tr.ID, 0); err != nil {
+					t.Fatalf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+				}
+				time.Sleep(tt.ttl / 2)
+			} else {
+				time.Sleep(tt.ttl * 2)
+			}
+
+			if got := ss.HasParser(tr.ID); got != tt.wantAlive {
+				t.Errorf("SingleSpoe.HasParser() got = %v, want %v", got, tt.wantAlive)
+			}
+			if _, err := ss.Transaction.GetTransactionFile(tr.ID); (err == nil) != tt.wantAlive {
+				t.Errorf("GetTransactionFile() error = %v, want transaction file %v", err, tt.wantAlive)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_VersionFencing(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxVersionDrift int64
+		commitsAhead    int
+		wantErrCode     int
+		wantVersion     int64
+	}{
+		{name: "Should reject commit after master version advanced", maxVersionDrift: 0, commitsAhead: 1, wantErrCode: conf.ErrVersionMismatch, wantVersion: 2},
+		{name: "Should commit within allowed drift", maxVersionDrift: 1, commitsAhead: 1, wantVersion: 3},
+		{name: "Should reject commit past allowed drift", maxVersionDrift: 1, commitsAhead: 2, wantErrCode: conf.ErrVersionConflict, wantVersion: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			transactionDir, _, err := misc.CreateTempDir("", false)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			defer func() {
+				_ = remove(configFile)
+				_ = remove(dir)
+				_ = remove(transactionDir)
+			}()
+			ss, err := newSingleSpoe(Params{
+				SpoeDir:           dir,
+				TransactionDir:    transactionDir,
+				ConfigurationFile: filepath.Join(dir, configFile),
+				MaxVersionDrift:   tt.maxVersionDrift,
+			})
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			fenced, err := ss.Transaction.StartTransaction(1)
+			if err != nil {
+				t.Fatalf("StartTransaction() error = %v", err)
+			}
+			defer func() {
+				_ = ss.Transaction.DeleteTransaction(fenced.ID)
+			}()
+			for i := 0; i < tt.commitsAhead; i++ {
+				v, _ := ss.GetVersion("")
+				ahead, err := ss.Transaction.StartTransaction(v)
+				if err != nil {
+					t.Fatalf("StartTransaction() error = %v", err)
+				}
+				if _, err = ss.Transaction.CommitTransaction(ahead.ID); err != nil {
+					t.Fatalf("CommitTransaction() error = %v", err)
+				}
+			}
+			if err = ss.CreateMessage("[ip-reputation]", &models.SpoeMessage{Name: misc.StringP("fenced-message")}, fenced.ID, 0); err != nil {
+				t.Fatalf("SingleSpoe.CreateMessage() error = %v", err)
+			}
+
+			_, err = ss.Transaction.CommitTransaction(fenced.ID)
+			if tt.wantErrCode == 0 {
+				if err != nil {
+					t.Errorf("CommitTransaction() error = %v, want nil", err)
+				}
+				if _, _, err = ss.GetMessage("[ip-reputation]", "fenced-message", ""); err != nil {
+					t.Errorf("SingleSpoe.GetMessage() error = %v", err)
+				}
+			}
+			if tt.wantErrCode != 0 {
+				var confErr *conf.ConfError
+				if !errors.As(err, &confErr) || confErr.Code() != tt.wantErrCode {
+					t.Errorf("CommitTransaction() error = %v, want code %v", err, tt.wantErrCode)
+				}
+			}
+			if v, _ := ss.GetVersion(""); v != tt.wantVersion {
+				t.Errorf("SingleSpoe.GetVersion() got = %v, want %v", v, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_AddParserConcurrent(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	persistent := false
+	ss, err := newSingleSpoe(Params{
+		SpoeDir:                dir,
+		TransactionDir:         transactionDir,
+		ConfigurationFile:      filepath.Join(dir, configFile),
+		PersistentTransactions: &persistent,
+	})
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+
+	for round := 0; round < 50; round++ {
+		id := fmt.Sprintf("concurrent-%d", round)
+		var added int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				<-start
+				if err := ss.AddParser(id); err == nil {
+					atomic.AddInt32(&added, 1)
+				}
+			}()
+			// master parser accessors race with swaps of the master parser
+			go func() {
+				defer wg.Done()
+				<-start
+				p, _ := ss.GetParser("")
+				ss.setParser(p)
+				_ = ss.IncrementTransactionVersion("")
+				_, _ = ss.GetTransactionSize(id)
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if added != 1 {
+			t.Errorf("SingleSpoe.AddParser() succeeded %d times, want 1", added)
+		}
+	}
+}
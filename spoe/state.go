@@ -0,0 +1,100 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/haproxytech/config-parser/v3/spoe"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// spoeState is the serialized form of SingleSpoe, parsers are stored as configuration
+// text, the version is part of the master configuration
+type spoeState struct {
+	Master       string            `json:"master"`
+	Transactions map[string]string `json:"transactions,omitempty"`
+}
+
+// MarshalBinary encodes the master parser and all transaction parsers,
+// lazy loaded transactions are loaded first.
+func (c *SingleSpoe) MarshalBinary() ([]byte, error) {
+	master, err := c.GetParser("")
+	if err != nil {
+		return nil, err
+	}
+	state := spoeState{
+		Master:       master.String(),
+		Transactions: map[string]string{},
+	}
+	for _, t := range c.GetParserTransactions() {
+		p, err := c.GetParser(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		state.Transactions[t.ID] = p.String()
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalBinary restores the state encoded with MarshalBinary, replacing the master
+// parser and all transaction parsers. The configuration file is not written, with
+// persistent transactions the file of each restored transaction is.
+func (c *SingleSpoe) UnmarshalBinary(data []byte) error {
+	var state spoeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("cannot decode state: %s", err.Error()))
+	}
+
+	master := &spoe.Parser{}
+	if err := master.ParseData(state.Master); err != nil {
+		return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot parse master configuration: %s", err.Error()))
+	}
+	parsers := make(map[string]*spoe.Parser, len(state.Transactions))
+	for tID, config := range state.Transactions {
+		p := &spoe.Parser{}
+		if err := p.ParseData(config); err != nil {
+			return conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot parse transaction %s: %s", tID, err.Error()))
+		}
+		parsers[tID] = p
+	}
+
+	c.parsersMu.Lock()
+	c.Parser = master
+	c.parsers = parsers
+	c.transactionFileIndex = map[string]string{}
+	c.transactionStats = map[string]*transactionStats{}
+	c.parsersMu.Unlock()
+	c.notifyVersionChange()
+
+	if !c.Transaction.PersistentTransactions {
+		return nil
+	}
+	if err := os.MkdirAll(c.Transaction.TransactionDir, 0755); err != nil {
+		return conf.NewConfError(conf.ErrGeneralError, fmt.Sprintf("cannot create transaction dir: %s", err.Error()))
+	}
+	for tID := range parsers {
+		tFile := filepath.Join(c.Transaction.TransactionDir, filepath.Base(filepath.Clean(c.Transaction.ConfigurationFile))+"."+tID)
+		if err := c.Save(tFile, tID); err != nil {
+			return conf.NewConfError(conf.ErrErrorChangingConfig, fmt.Sprintf("cannot write transaction %s: %s", tID, err.Error()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_MarshalBinary(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	if err = ss.DeleteAgent("[ip-reputation]", "iprep-agent", tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.DeleteAgent() error = %v", err)
+	}
+
+	data, err := ss.MarshalBinary()
+	if err != nil {
+		t.Fatalf("SingleSpoe.MarshalBinary() error = %v", err)
+	}
+
+	restored, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	_ = restored.DeleteParser(tr.ID)
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("SingleSpoe.UnmarshalBinary() error = %v", err)
+	}
+	assert.Equal(t, ss.Parser.String(), restored.Parser.String())
+	p, err := restored.GetParser(tr.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetParser() error = %v", err)
+	}
+	want, _ := ss.GetParser(tr.ID)
+	assert.Equal(t, want.String(), p.String())
+
+	if err = restored.UnmarshalBinary([]byte("{")); err == nil {
+		t.Errorf("SingleSpoe.UnmarshalBinary() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_UnmarshalBinaryCommit(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	restoredDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+		_ = remove(restoredDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	if err = ss.DeleteAgent("[ip-reputation]", "iprep-agent", tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.DeleteAgent() error = %v", err)
+	}
+	data, err := ss.MarshalBinary()
+	if err != nil {
+		t.Fatalf("SingleSpoe.MarshalBinary() error = %v", err)
+	}
+
+	// the restored client has none of the transaction files
+	params.TransactionDir = restoredDir
+	restored, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("SingleSpoe.UnmarshalBinary() error = %v", err)
+	}
+	if _, err = restored.Transaction.CommitTransaction(tr.ID); err != nil {
+		t.Fatalf("Transaction.CommitTransaction() error = %v", err)
+	}
+	if _, _, err = restored.GetAgent("[ip-reputation]", "iprep-agent", ""); err == nil {
+		t.Errorf("SingleSpoe.GetAgent() error = nil, want agent deleted by the restored transaction")
+	}
+}
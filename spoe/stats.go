@@ -0,0 +1,74 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	parser "github.com/haproxytech/config-parser/v3"
+)
+
+// ScopeStats holds the number of sections of each type in a scope
+type ScopeStats struct {
+	Agents   int `json:"agents"`
+	Groups   int `json:"groups"`
+	Messages int `json:"messages"`
+}
+
+// ConfigStats holds the number of scopes and sections of each type in the
+// configuration, ByScope is keyed by scope name
+type ConfigStats struct {
+	TotalAgents   int                   `json:"total_agents"`
+	TotalGroups   int                   `json:"total_groups"`
+	TotalMessages int                   `json:"total_messages"`
+	TotalScopes   int                   `json:"total_scopes"`
+	ByScope       map[string]ScopeStats `json:"by_scope"`
+}
+
+// GetConfigStats returns the number of scopes and sections of each type.
+// Returns error on fail.
+func (c *SingleSpoe) GetConfigStats(transactionID string) (*ConfigStats, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ConfigStats{ByScope: map[string]ScopeStats{}}
+	for scope := range p.Parsers {
+		if !p.IsScope(scope) {
+			continue
+		}
+		s := ScopeStats{}
+		for _, section := range sectionTypes {
+			names, err := p.SectionsGet(scope, section)
+			if err != nil {
+				continue
+			}
+			switch section { //nolint:exhaustive
+			case parser.SPOEAgent:
+				s.Agents = len(names)
+			case parser.SPOEGroup:
+				s.Groups = len(names)
+			case parser.SPOEMessage:
+				s.Messages = len(names)
+			}
+		}
+		stats.ByScope[scope] = s
+		stats.TotalAgents += s.Agents
+		stats.TotalGroups += s.Groups
+		stats.TotalMessages += s.Messages
+		stats.TotalScopes++
+	}
+	return stats, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_GetConfigStats(t *testing.T) {
+	config := basicConfig + `
+[other]
+spoe-agent other-agent
+    groups other-group
+
+spoe-group other-group
+    messages first second
+
+spoe-message first
+    args src
+    event on-client-session
+
+spoe-message second
+    args dst
+    event on-server-session
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	got, err := ss.GetConfigStats("")
+	if err != nil {
+		t.Fatalf("SingleSpoe.GetConfigStats() error = %v", err)
+	}
+	want := &ConfigStats{
+		TotalAgents:   2,
+		TotalGroups:   2,
+		TotalMessages: 3,
+		TotalScopes:   2,
+		ByScope: map[string]ScopeStats{
+			"[ip-reputation]": {Agents: 1, Groups: 1, Messages: 1},
+			"[other]":         {Agents: 1, Groups: 1, Messages: 2},
+		},
+	}
+	assert.Equal(t, want, got)
+
+	if _, err = ss.GetConfigStats("unknown"); err == nil {
+		t.Errorf("SingleSpoe.GetConfigStats() error = nil, want error")
+	}
+}
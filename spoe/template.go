@@ -0,0 +1,113 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/haproxytech/config-parser/v3/spoe"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// ScopeTemplate holds the agents, groups and messages of a scope, without the scope
+// header, as a text/template expanded with the variables given to ApplyTemplate,
+// e.g. "spoe-agent {{.agent}}\n    use-backend {{.backend}}\n"
+type ScopeTemplate struct {
+	Description string
+	Text        string
+}
+
+// TemplateRegistry holds named scope templates, it is safe for concurrent use
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]*ScopeTemplate
+	parsed    map[string]*template.Template
+}
+
+// NewTemplateRegistry returns an empty TemplateRegistry
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{
+		templates: map[string]*ScopeTemplate{},
+		parsed:    map[string]*template.Template{},
+	}
+}
+
+// Register adds a template under name. Returns error if the template can not be
+// parsed or name is already registered.
+func (r *TemplateRegistry) Register(name string, tmpl *ScopeTemplate) error {
+	if name == "" || tmpl == nil {
+		return conf.NewConfError(conf.ErrValidationError, "template name and template are mandatory")
+	}
+	parsed, err := template.New(name).Option("missingkey=error").Parse(tmpl.Text)
+	if err != nil {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("cannot parse template %s: %s", name, err.Error()))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[name]; ok {
+		return conf.NewConfError(conf.ErrObjectAlreadyExists, fmt.Sprintf("template %s already exists", name))
+	}
+	r.templates[name] = tmpl
+	r.parsed[name] = parsed
+	return nil
+}
+
+// Get returns the template registered under name. Returns error if it does not exist.
+func (r *TemplateRegistry) Get(name string) (*ScopeTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("template %s does not exist", name))
+	}
+	return tmpl, nil
+}
+
+// ApplyTemplate expands template name with vars and adds the resulting sections to
+// scope of ss, scope is created if it does not exist. If scope already has sections
+// with the same names nothing is added and an error listing all conflicts is
+// returned. One of version or transactionID is mandatory.
+// Returns error on fail, nil on success.
+func (r *TemplateRegistry) ApplyTemplate(ss *SingleSpoe, name, scope string, vars map[string]string, transactionID string, version int64) error {
+	r.mu.RLock()
+	parsed, ok := r.parsed[name]
+	r.mu.RUnlock()
+	if !ok {
+		return conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("template %s does not exist", name))
+	}
+	var b strings.Builder
+	if err := parsed.Execute(&b, vars); err != nil {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("cannot expand template %s: %s", name, err.Error()))
+	}
+	src := &spoe.Parser{}
+	if err := src.ParseData(scope + "\n" + b.String()); err != nil {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("cannot parse template %s: %s", name, err.Error()))
+	}
+
+	p, t, err := ss.loadDataForChange(transactionID, version)
+	if err != nil {
+		return err
+	}
+	if err := ss.insertScopeSections(p, src, scope); err != nil {
+		return ss.Transaction.HandleError(scope, "", "", t, transactionID == "", err)
+	}
+	return ss.Transaction.SaveData(p, t, transactionID == "")
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+const ipReputationTemplate = `spoe-agent {{.agent}}
+    messages check-{{.agent}}
+    use-backend {{.backend}}
+
+spoe-message check-{{.agent}}
+    args ip=src
+    event on-client-session
+`
+
+func TestTemplateRegistry_Register(t *testing.T) {
+	r := NewTemplateRegistry()
+	tmpl := &ScopeTemplate{Description: "IP reputation agent", Text: ipReputationTemplate}
+	if err := r.Register("ip-reputation", tmpl); err != nil {
+		t.Errorf("TemplateRegistry.Register() error = %v", err)
+	}
+	if err := r.Register("ip-reputation", tmpl); err == nil {
+		t.Errorf("TemplateRegistry.Register() error = nil, want error")
+	}
+	if err := r.Register("broken", &ScopeTemplate{Text: "spoe-agent {{.agent"}); err == nil {
+		t.Errorf("TemplateRegistry.Register() error = nil, want error")
+	}
+	if err := r.Register("empty", nil); err == nil {
+		t.Errorf("TemplateRegistry.Register() error = nil, want error")
+	}
+
+	got, err := r.Get("ip-reputation")
+	if err != nil {
+		t.Errorf("TemplateRegistry.Get() error = %v", err)
+	}
+	assert.Equal(t, tmpl, got)
+	if _, err = r.Get("broken"); err == nil {
+		t.Errorf("TemplateRegistry.Get() error = nil, want error")
+	}
+}
+
+func TestTemplateRegistry_ApplyTemplate(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	r := NewTemplateRegistry()
+	if err = r.Register("ip-reputation", &ScopeTemplate{Text: ipReputationTemplate}); err != nil {
+		t.Fatalf("TemplateRegistry.Register() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		template string
+		scope    string
+		vars     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "Should apply template to new scope",
+			template: "ip-reputation",
+			scope:    "[new-scope]",
+			vars:     map[string]string{"agent": "new-agent", "backend": "new-agents"},
+		},
+		{
+			name:     "Should apply template to existing scope",
+			template: "ip-reputation",
+			scope:    "[ip-reputation]",
+			vars:     map[string]string{"agent": "other-agent", "backend": "agents"},
+		},
+		{
+			name:     "Should fail on existing sections",
+			template: "ip-reputation",
+			scope:    "[ip-reputation]",
+			vars:     map[string]string{"agent": "iprep-agent", "backend": "agents"},
+			wantErr:  true,
+		},
+		{
+			name:     "Should fail on missing variable",
+			template: "ip-reputation",
+			scope:    "[new-scope]",
+			vars:     map[string]string{"agent": "missing-backend"},
+			wantErr:  true,
+		},
+		{
+			name:     "Should fail on unknown template",
+			template: "unknown",
+			scope:    "[new-scope]",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Fatalf("newSingleSpoe() error = %v", err)
+			}
+			v, _ := ss.GetVersion("")
+			err = r.ApplyTemplate(ss, tt.template, tt.scope, tt.vars, "", v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TemplateRegistry.ApplyTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			_, agent, err := ss.GetAgent(tt.scope, tt.vars["agent"], "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetAgent() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.vars["backend"], agent.UseBackend)
+			if _, _, err = ss.GetMessage(tt.scope, "check-"+tt.vars["agent"], ""); err != nil {
+				t.Errorf("SingleSpoe.GetMessage() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+// MaxProcessingTimeout is the highest recommended processing timeout of an agent
+const MaxProcessingTimeout = 60 * time.Second
+
+// GetSPOEAgentHelloTimeout returns the hello timeout of an agent, 0 if not set.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentHelloTimeout(scope, agentName string, transactionID string) (time.Duration, error) {
+	return c.getAgentTimeout(scope, agentName, "timeout hello", transactionID)
+}
+
+// SetSPOEAgentHelloTimeout sets the hello timeout of an agent. With validation
+// warnings are returned when the timeout set exceeds the idle timeout.
+// One of version or transactionID is mandatory. Returns warnings and error on
+// fail, nothing is set on error.
+func (c *SingleSpoe) SetSPOEAgentHelloTimeout(scope, agentName string, d time.Duration, transactionID string, version int64) ([]*LintWarning, error) {
+	if err := c.setAgentTimeout(scope, agentName, "timeout hello", d, transactionID, version); err != nil {
+		return nil, err
+	}
+	return c.helloTimeoutWarnings(scope, agentName, transactionID), nil
+}
+
+// GetSPOEAgentIdleTimeout returns the idle timeout of an agent, 0 if not set.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentIdleTimeout(scope, agentName string, transactionID string) (time.Duration, error) {
+	return c.getAgentTimeout(scope, agentName, "timeout idle", transactionID)
+}
+
+// SetSPOEAgentIdleTimeout sets the idle timeout of an agent. With validation
+// warnings are returned when the timeout set is below the hello timeout.
+// One of version or transactionID is mandatory. Returns warnings and error on
+// fail, nothing is set on error.
+func (c *SingleSpoe) SetSPOEAgentIdleTimeout(scope, agentName string, d time.Duration, transactionID string, version int64) ([]*LintWarning, error) {
+	if err := c.setAgentTimeout(scope, agentName, "timeout idle", d, transactionID, version); err != nil {
+		return nil, err
+	}
+	return c.helloTimeoutWarnings(scope, agentName, transactionID), nil
+}
+
+// helloTimeoutWarnings returns the hello timeout warning of an agent with validation
+func (c *SingleSpoe) helloTimeoutWarnings(scope, agentName, transactionID string) []*LintWarning {
+	warnings := []*LintWarning{}
+	if !c.Transaction.UseValidation {
+		return warnings
+	}
+	if w := c.lintHelloTimeout(scope, agentName, transactionID); w != nil {
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
+
+// GetSPOEAgentProcessingTimeout returns the processing timeout of an agent, 0 if not set.
+// Returns error on fail or if agent does not exist.
+func (c *SingleSpoe) GetSPOEAgentProcessingTimeout(scope, agentName string, transactionID string) (time.Duration, error) {
+	return c.getAgentTimeout(scope, agentName, "timeout processing", transactionID)
+}
+
+// SetSPOEAgentProcessingTimeout sets the processing timeout of an agent. With validation
+// warnings are returned when the timeout set exceeds MaxProcessingTimeout.
+// One of version or transactionID is mandatory. Returns warnings and error on fail,
+// nothing is set on error.
+func (c *SingleSpoe) SetSPOEAgentProcessingTimeout(scope, agentName string, d time.Duration, transactionID string, version int64) ([]*LintWarning, error) {
+	if err := c.setAgentTimeout(scope, agentName, "timeout processing", d, transactionID, version); err != nil {
+		return nil, err
+	}
+	warnings := []*LintWarning{}
+	if !c.Transaction.UseValidation || d <= MaxProcessingTimeout {
+		return warnings, nil
+	}
+	return append(warnings, &LintWarning{
+		Scope:   scope,
+		Section: parser.SPOEAgent,
+		Name:    agentName,
+		Message: fmt.Sprintf("timeout processing %s exceeds recommended maximum %s", d, MaxProcessingTimeout),
+	}), nil
+}
+
+func (c *SingleSpoe) getAgentTimeout(scope, agentName, directive string, transactionID string) (time.Duration, error) {
+	data, err := c.getSectionDirective(scope, parser.SPOEAgent, agentName, directive, transactionID)
+	if err != nil {
+		return 0, err
+	}
+	d, ok := data.(*types.StringC)
+	if !ok || d.Value == "" {
+		return 0, nil
+	}
+	ms := misc.ParseTimeout(d.Value)
+	if ms == nil {
+		return 0, conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("invalid %s %s", directive, d.Value))
+	}
+	return time.Duration(*ms) * time.Millisecond, nil
+}
+
+// setAgentTimeout sets a timeout of an agent in milliseconds, like the agent model does
+func (c *SingleSpoe) setAgentTimeout(scope, agentName, directive string, d time.Duration, transactionID string, version int64) error {
+	if d < time.Millisecond {
+		return conf.NewConfError(conf.ErrValidationError, fmt.Sprintf("%s must be at least 1ms", directive))
+	}
+	return c.editSection(scope, parser.SPOEAgent, agentName, transactionID, version, func(p *spoe.Parser) error {
+		value := strconv.FormatInt(d.Milliseconds(), 10)
+		return p.Set(scope, parser.SPOEAgent, agentName, directive, &types.StringC{Value: value})
+	})
+}
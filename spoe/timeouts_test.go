@@ -0,0 +1,282 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_SetSPOEAgentHelloTimeout(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		timeout     time.Duration
+		want        time.Duration
+		wantWarning bool
+		wantErr     bool
+	}{
+		{
+			name:      "Should set hello timeout",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			timeout:   5 * time.Second,
+			want:      5 * time.Second,
+		},
+		{
+			name:        "Should warn when exceeding idle timeout",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			timeout:     5 * time.Minute,
+			want:        5 * time.Minute,
+			wantWarning: true,
+		},
+		{
+			name:      "Should fail on zero timeout",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			timeout:   0,
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			timeout:   5 * time.Second,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentHelloTimeout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			warnings, err := ss.SetSPOEAgentHelloTimeout(tt.scope, tt.agentName, tt.timeout, "", v)
+			if (len(warnings) > 0) != tt.wantWarning || (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentHelloTimeout() error = %v, wantErr %v, wantWarning %v", err, tt.wantErr, tt.wantWarning)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentHelloTimeout(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentHelloTimeout() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SingleSpoe.GetSPOEAgentHelloTimeout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentIdleTimeout(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		timeout     time.Duration
+		want        time.Duration
+		wantWarning bool
+		wantErr     bool
+	}{
+		{
+			name:      "Should set idle timeout",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			timeout:   30 * time.Second,
+			want:      30 * time.Second,
+		},
+		{
+			name:        "Should warn when below hello timeout",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			timeout:     time.Second,
+			want:        time.Second,
+			wantWarning: true,
+		},
+		{
+			name:      "Should fail on sub-millisecond timeout",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			timeout:   time.Microsecond,
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			timeout:   30 * time.Second,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentIdleTimeout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			warnings, err := ss.SetSPOEAgentIdleTimeout(tt.scope, tt.agentName, tt.timeout, "", v)
+			if (len(warnings) > 0) != tt.wantWarning || (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentIdleTimeout() error = %v, wantErr %v, wantWarning %v", err, tt.wantErr, tt.wantWarning)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentIdleTimeout(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentIdleTimeout() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SingleSpoe.GetSPOEAgentIdleTimeout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingleSpoe_SetSPOEAgentProcessingTimeout(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	tests := []struct {
+		name        string
+		scope       string
+		agentName   string
+		timeout     time.Duration
+		want        time.Duration
+		wantWarning bool
+		wantErr     bool
+	}{
+		{
+			name:      "Should set processing timeout",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			timeout:   500 * time.Millisecond,
+			want:      500 * time.Millisecond,
+		},
+		{
+			name:        "Should warn when exceeding recommended maximum",
+			scope:       "[ip-reputation]",
+			agentName:   "iprep-agent",
+			timeout:     2 * time.Minute,
+			want:        2 * time.Minute,
+			wantWarning: true,
+		},
+		{
+			name:      "Should fail on sub-millisecond timeout",
+			scope:     "[ip-reputation]",
+			agentName: "iprep-agent",
+			timeout:   time.Microsecond,
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail on unknown agent",
+			scope:     "[ip-reputation]",
+			agentName: "unknown-agent",
+			timeout:   500 * time.Millisecond,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := newSingleSpoe(params)
+			if err != nil {
+				t.Errorf("SingleSpoe.SetSPOEAgentProcessingTimeout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			v, _ := ss.GetVersion("")
+			warnings, err := ss.SetSPOEAgentProcessingTimeout(tt.scope, tt.agentName, tt.timeout, "", v)
+			if (len(warnings) > 0) != tt.wantWarning || (err != nil) != tt.wantErr {
+				t.Errorf("SingleSpoe.SetSPOEAgentProcessingTimeout() error = %v, wantErr %v, wantWarning %v", err, tt.wantErr, tt.wantWarning)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := ss.GetSPOEAgentProcessingTimeout(tt.scope, tt.agentName, "")
+			if err != nil {
+				t.Errorf("SingleSpoe.GetSPOEAgentProcessingTimeout() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SingleSpoe.GetSPOEAgentProcessingTimeout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
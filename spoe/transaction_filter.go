@@ -0,0 +1,90 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"os"
+	"time"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// TransactionFilter holds criteria transactions are matched against, zero valued
+// fields are not checked
+type TransactionFilter struct {
+	MinVersion    int64
+	MaxVersion    int64
+	CreatedBefore time.Time
+	CreatedAfter  time.Time
+	HasScope      string
+}
+
+// FilterTransactions returns transactions in progress matching all criteria of filter.
+// Transactions found on Init use the modification time of their transaction file as
+// creation time. Returns error on fail.
+func (c *SingleSpoe) FilterTransactions(filter TransactionFilter) (models.Transactions, error) {
+	matched := models.Transactions{}
+	for _, t := range c.GetParserTransactions() {
+		if filter.MinVersion != 0 && t.Version < filter.MinVersion {
+			continue
+		}
+		if filter.MaxVersion != 0 && t.Version > filter.MaxVersion {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() || !filter.CreatedAfter.IsZero() {
+			created, err := c.transactionCreatedAt(t.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !filter.CreatedBefore.IsZero() && !created.Before(filter.CreatedBefore) {
+				continue
+			}
+			if !filter.CreatedAfter.IsZero() && !created.After(filter.CreatedAfter) {
+				continue
+			}
+		}
+		if filter.HasScope != "" {
+			p, err := c.GetParser(t.ID)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.Parsers[filter.HasScope]; !ok || !p.IsScope(filter.HasScope) {
+				continue
+			}
+		}
+		matched = append(matched, t)
+	}
+	return matched, nil
+}
+
+func (c *SingleSpoe) transactionCreatedAt(transactionID string) (time.Time, error) {
+	c.parsersMu.RLock()
+	stats, ok := c.transactionStats[transactionID]
+	c.parsersMu.RUnlock()
+	if ok && stats.started {
+		return stats.created, nil
+	}
+	file, err := c.Transaction.GetTransactionFile(transactionID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, conf.NewConfError(conf.ErrCannotReadConfFile, err.Error())
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+func TestSingleSpoe_FilterTransactions(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	before := time.Now()
+	first, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(first.ID)
+	}()
+	scope := models.SpoeScope("[other]")
+	if err = ss.CreateScope(&scope, first.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.CreateScope() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	between := time.Now()
+	second, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(second.ID)
+	}()
+
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		want   []string
+	}{
+		{
+			name:   "Should match all with empty filter",
+			filter: TransactionFilter{},
+			want:   []string{first.ID, second.ID},
+		},
+		{
+			name:   "Should match by version",
+			filter: TransactionFilter{MinVersion: 1, MaxVersion: 1},
+			want:   []string{first.ID, second.ID},
+		},
+		{
+			name:   "Should match nothing above version",
+			filter: TransactionFilter{MinVersion: 2},
+			want:   []string{},
+		},
+		{
+			name:   "Should match by creation time",
+			filter: TransactionFilter{CreatedAfter: before, CreatedBefore: between},
+			want:   []string{first.ID},
+		},
+		{
+			name:   "Should match by scope",
+			filter: TransactionFilter{HasScope: "[other]"},
+			want:   []string{first.ID},
+		},
+		{
+			name:   "Should combine criteria",
+			filter: TransactionFilter{HasScope: "[other]", CreatedAfter: between},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ss.FilterTransactions(tt.filter)
+			if err != nil {
+				t.Errorf("SingleSpoe.FilterTransactions() error = %v", err)
+				return
+			}
+			ids := map[string]bool{}
+			for _, tr := range got {
+				ids[tr.ID] = true
+			}
+			if len(ids) != len(tt.want) {
+				t.Errorf("SingleSpoe.FilterTransactions() got = %v, want %v", ids, tt.want)
+				return
+			}
+			for _, id := range tt.want {
+				if !ids[id] {
+					t.Errorf("SingleSpoe.FilterTransactions() got = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
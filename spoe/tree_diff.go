@@ -0,0 +1,158 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"sort"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+)
+
+// TreeNode identifies a section of a scope
+type TreeNode struct {
+	Scope       string
+	SectionType parser.Section
+	SectionName string
+}
+
+// DirectiveDiff holds the configuration lines of a directive in both configurations,
+// Old is empty for added directives and New for removed ones
+type DirectiveDiff struct {
+	Old string
+	New string
+}
+
+// TreeNodeDiff is a section present in both configurations with changed directives,
+// DirectiveDiffs is keyed by directive name
+type TreeNodeDiff struct {
+	TreeNode
+	DirectiveDiffs map[string]DirectiveDiff
+}
+
+// TreeDiff is the structural difference between two configurations, sections are
+// ordered by scope, section type and name
+type TreeDiff struct {
+	Added    []TreeNode
+	Removed  []TreeNode
+	Modified []TreeNodeDiff
+}
+
+// ConfigTreeDiff returns the sections added, removed and modified in configuration of
+// transaction txB compared to transaction txA, "" being the master configuration.
+// Returns error on fail.
+func (c *SingleSpoe) ConfigTreeDiff(txA, txB string) (*TreeDiff, error) {
+	a, err := c.GetParser(txA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.GetParser(txB)
+	if err != nil {
+		return nil, err
+	}
+
+	nodesA, nodesB := treeNodes(a), treeNodes(b)
+	diff := &TreeDiff{Added: []TreeNode{}, Removed: []TreeNode{}, Modified: []TreeNodeDiff{}}
+	for _, node := range sortedTreeNodes(nodesA) {
+		directivesB, ok := nodesB[node]
+		if !ok {
+			diff.Removed = append(diff.Removed, node)
+			continue
+		}
+		directives := directiveDiffs(nodesA[node], directivesB)
+		if len(directives) > 0 {
+			diff.Modified = append(diff.Modified, TreeNodeDiff{TreeNode: node, DirectiveDiffs: directives})
+		}
+	}
+	for _, node := range sortedTreeNodes(nodesB) {
+		if _, ok := nodesA[node]; !ok {
+			diff.Added = append(diff.Added, node)
+		}
+	}
+	return diff, nil
+}
+
+// ConfigDiff is the structural difference between two transactions
+type ConfigDiff = TreeDiff
+
+// DiffTransactions returns the sections added, removed and modified in transaction
+// txB compared to transaction txA, "" being the master configuration. Sections of
+// all types are compared directive by directive, see ConfigTreeDiff.
+// Returns error on fail.
+func (c *SingleSpoe) DiffTransactions(txA, txB string) (*ConfigDiff, error) {
+	return c.ConfigTreeDiff(txA, txB)
+}
+
+// treeNodes returns the configuration lines of each directive of all sections
+func treeNodes(p *spoe.Parser) map[TreeNode]map[string]string {
+	nodes := map[TreeNode]map[string]string{}
+	for scope := range p.Parsers {
+		if !p.IsScope(scope) {
+			continue
+		}
+		for _, section := range sectionTypes {
+			for name, sectionParsers := range p.Parsers[scope][section] {
+				directives := map[string]string{}
+				for directive, directiveParser := range sectionParsers.Parsers {
+					lines, _, err := directiveParser.ResultAll()
+					if err != nil || len(lines) == 0 {
+						continue
+					}
+					data := make([]string, 0, len(lines))
+					for _, line := range lines {
+						data = append(data, line.Data)
+					}
+					directives[directive] = strings.Join(data, "\n")
+				}
+				nodes[TreeNode{Scope: scope, SectionType: section, SectionName: name}] = directives
+			}
+		}
+	}
+	return nodes
+}
+
+func sortedTreeNodes(nodes map[TreeNode]map[string]string) []TreeNode {
+	sorted := make([]TreeNode, 0, len(nodes))
+	for node := range nodes {
+		sorted = append(sorted, node)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Scope != sorted[j].Scope {
+			return sorted[i].Scope < sorted[j].Scope
+		}
+		if sorted[i].SectionType != sorted[j].SectionType {
+			return sorted[i].SectionType < sorted[j].SectionType
+		}
+		return sorted[i].SectionName < sorted[j].SectionName
+	})
+	return sorted
+}
+
+func directiveDiffs(a, b map[string]string) map[string]DirectiveDiff {
+	diffs := map[string]DirectiveDiff{}
+	for directive, old := range a {
+		if b[directive] != old {
+			diffs[directive] = DirectiveDiff{Old: old, New: b[directive]}
+		}
+	}
+	for directive, value := range b {
+		if _, ok := a[directive]; !ok {
+			diffs[directive] = DirectiveDiff{New: value}
+		}
+	}
+	return diffs
+}
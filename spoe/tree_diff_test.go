@@ -0,0 +1,158 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+func TestSingleSpoe_ConfigTreeDiff(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(tr.ID)
+	}()
+	scope := "[ip-reputation]"
+	name := "new-message"
+	if err = ss.CreateMessage(scope, &models.SpoeMessage{Name: &name}, tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.CreateMessage() error = %v", err)
+	}
+	if err = ss.DeleteGroup(scope, "mygroup", tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.DeleteGroup() error = %v", err)
+	}
+	if _, err = ss.SetSPOEAgentIdleTimeout(scope, "iprep-agent", time.Minute, tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentIdleTimeout() error = %v", err)
+	}
+
+	got, err := ss.ConfigTreeDiff("", tr.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.ConfigTreeDiff() error = %v", err)
+	}
+	want := &TreeDiff{
+		Added:   []TreeNode{{Scope: scope, SectionType: parser.SPOEMessage, SectionName: "new-message"}},
+		Removed: []TreeNode{{Scope: scope, SectionType: parser.SPOEGroup, SectionName: "mygroup"}},
+		Modified: []TreeNodeDiff{
+			{
+				TreeNode: TreeNode{Scope: scope, SectionType: parser.SPOEAgent, SectionName: "iprep-agent"},
+				DirectiveDiffs: map[string]DirectiveDiff{
+					"timeout idle": {Old: "timeout idle 2m", New: "timeout idle 60000"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, want, got)
+
+	got, err = ss.ConfigTreeDiff(tr.ID, tr.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.ConfigTreeDiff() error = %v", err)
+	}
+	assert.Equal(t, &TreeDiff{Added: []TreeNode{}, Removed: []TreeNode{}, Modified: []TreeNodeDiff{}}, got)
+
+	if _, err = ss.ConfigTreeDiff("", "unknown"); err == nil {
+		t.Errorf("SingleSpoe.ConfigTreeDiff() error = nil, want error")
+	}
+}
+
+func TestSingleSpoe_DiffTransactions(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	txA, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	txB, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(txA.ID)
+		_ = ss.Transaction.DeleteTransaction(txB.ID)
+	}()
+	scope := "[ip-reputation]"
+	name := "new-message"
+	if err = ss.CreateMessage(scope, &models.SpoeMessage{Name: &name}, txA.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.CreateMessage() error = %v", err)
+	}
+	if err = ss.DeleteGroup(scope, "mygroup", txB.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.DeleteGroup() error = %v", err)
+	}
+
+	got, err := ss.DiffTransactions(txA.ID, txB.ID)
+	if err != nil {
+		t.Fatalf("SingleSpoe.DiffTransactions() error = %v", err)
+	}
+	want := &ConfigDiff{
+		Added: []TreeNode{},
+		Removed: []TreeNode{
+			{Scope: scope, SectionType: parser.SPOEGroup, SectionName: "mygroup"},
+			{Scope: scope, SectionType: parser.SPOEMessage, SectionName: "new-message"},
+		},
+		Modified: []TreeNodeDiff{},
+	}
+	assert.Equal(t, want, got)
+}
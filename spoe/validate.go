@@ -0,0 +1,138 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+)
+
+// ValidationError reports a section which HAProxy would refuse to load
+type ValidationError struct {
+	Scope   string
+	Section parser.Section
+	Name    string
+	Message string
+}
+
+// Error implementation for ValidationError
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s %s %s: %s", e.Scope, e.Section, e.Name, e.Message)
+}
+
+// Validate checks all scopes of the configuration for agents without a backend and
+// for agents and groups referencing sections which do not exist. Returns errors
+// found ordered by scope, and error on fail.
+func (c *SingleSpoe) Validate(transactionID string) ([]ValidationError, error) {
+	p, err := c.GetParser(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return c.validateParser(p), nil
+}
+
+// ValidateAll runs Validate on the master configuration and on all transactions in
+// parallel. Returns validation errors keyed by transaction ID, "" for the master
+// configuration. Transactions without errors are left out, so an empty map means
+// everything is valid.
+func (c *SingleSpoe) ValidateAll() map[string][]ValidationError {
+	parsers := map[string]*spoe.Parser{}
+	_ = c.ForEachTransaction(func(id string, p *spoe.Parser) error {
+		parsers[id] = p
+		return nil
+	})
+	if p, err := c.GetParser(""); err == nil {
+		parsers[""] = p
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := map[string][]ValidationError{}
+	for id, p := range parsers {
+		wg.Add(1)
+		go func(id string, p *spoe.Parser) {
+			defer wg.Done()
+			errs := c.validateParser(p)
+			if len(errs) == 0 {
+				return
+			}
+			mu.Lock()
+			result[id] = errs
+			mu.Unlock()
+		}(id, p)
+	}
+	wg.Wait()
+	return result
+}
+
+func (c *SingleSpoe) validateParser(p *spoe.Parser) []ValidationError {
+	scopes := []string{}
+	for scope := range p.Parsers {
+		if p.IsScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	errs := []ValidationError{}
+	for _, scope := range scopes {
+		agents, _ := p.SectionsGet(scope, parser.SPOEAgent)
+		sort.Strings(agents)
+		for _, agent := range agents {
+			if _, err := p.Get(scope, parser.SPOEAgent, agent, "use-backend", false); err != nil {
+				errs = append(errs, ValidationError{Scope: scope, Section: parser.SPOEAgent, Name: agent, Message: "use-backend is missing"})
+			}
+			for _, m := range sectionList(scope, parser.SPOEAgent, agent, "messages", p) {
+				if !c.checkSectionExists(scope, parser.SPOEMessage, m, p) {
+					errs = append(errs, ValidationError{Scope: scope, Section: parser.SPOEAgent, Name: agent, Message: fmt.Sprintf("%s %s does not exist", parser.SPOEMessage, m)})
+				}
+			}
+			for _, g := range sectionList(scope, parser.SPOEAgent, agent, "groups", p) {
+				if !c.checkSectionExists(scope, parser.SPOEGroup, g, p) {
+					errs = append(errs, ValidationError{Scope: scope, Section: parser.SPOEAgent, Name: agent, Message: fmt.Sprintf("%s %s does not exist", parser.SPOEGroup, g)})
+				}
+			}
+		}
+		groups, _ := p.SectionsGet(scope, parser.SPOEGroup)
+		sort.Strings(groups)
+		for _, group := range groups {
+			for _, m := range groupMessages(scope, group, p) {
+				if !c.checkSectionExists(scope, parser.SPOEMessage, m, p) {
+					errs = append(errs, ValidationError{Scope: scope, Section: parser.SPOEGroup, Name: group, Message: fmt.Sprintf("%s %s does not exist", parser.SPOEMessage, m)})
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// sectionList returns the names listed in a directive of a section
+func sectionList(scope string, section parser.Section, name, directive string, p *spoe.Parser) []string {
+	data, err := p.Get(scope, section, name, directive, false)
+	if err != nil {
+		return []string{}
+	}
+	if d, ok := data.(*types.StringC); ok {
+		return strings.Fields(d.Value)
+	}
+	return []string{}
+}
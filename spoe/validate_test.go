@@ -0,0 +1,95 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_Validate(t *testing.T) {
+	config := basicConfig + `
+spoe-agent broken-agent
+    messages unknown-message
+    groups mygroup unknown-group
+`
+	dir, configFile, err := misc.CreateTempDir(config, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	scope := "[ip-reputation]"
+	want := []ValidationError{
+		{Scope: scope, Section: parser.SPOEAgent, Name: "broken-agent", Message: "use-backend is missing"},
+		{Scope: scope, Section: parser.SPOEAgent, Name: "broken-agent", Message: "spoe-message unknown-message does not exist"},
+		{Scope: scope, Section: parser.SPOEAgent, Name: "broken-agent", Message: "spoe-group unknown-group does not exist"},
+		{Scope: scope, Section: parser.SPOEGroup, Name: "mygroup", Message: "spoe-message mymessage does not exist"},
+	}
+	got, err := ss.Validate("")
+	if err != nil {
+		t.Errorf("SingleSpoe.Validate() error = %v", err)
+	}
+	assert.Equal(t, want, got)
+	if _, err = ss.Validate("unknown"); err == nil {
+		t.Errorf("SingleSpoe.Validate() error = nil, want error")
+	}
+
+	fixed, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(fixed.ID)
+	}()
+	if err = ss.DeleteAgent(scope, "broken-agent", fixed.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.DeleteAgent() error = %v", err)
+	}
+	if err = ss.SetSPOEGroupMessages(scope, "iprep-agent", "mygroup", []string{}, fixed.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEGroupMessages() error = %v", err)
+	}
+	unchanged, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	defer func() {
+		_ = ss.Transaction.DeleteTransaction(unchanged.ID)
+	}()
+
+	all := ss.ValidateAll()
+	assert.Equal(t, map[string][]ValidationError{"": want, unchanged.ID: want}, all)
+}
@@ -15,6 +15,32 @@
 
 package spoe
 
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v3"
+	"github.com/haproxytech/config-parser/v3/spoe"
+	"github.com/haproxytech/config-parser/v3/types"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+)
+
+// commitSubBuffer is the number of CommitInfo values buffered for a listener
+const commitSubBuffer = 16
+
+// CommitInfo describes a committed transaction, Scopes are the sorted names
+// of scopes the transaction changed. TransactionID is empty when the
+// configuration was replaced with ReplaceSPOEFile.
+type CommitInfo struct {
+	TransactionID string
+	Version       int64
+	Scopes        []string
+}
+
 // GetConfigurationVersion returns configuration version
 func (c *SingleSpoe) GetConfigurationVersion(transactionID string) (int64, error) {
 	_, err := c.GetParser(transactionID)
@@ -28,3 +54,181 @@ func (c *SingleSpoe) GetConfigurationVersion(transactionID string) (int64, error
 	}
 	return v, nil
 }
+
+// ReadSPOEFileVersion returns the version of a SPOE configuration file without
+// creating a SingleSpoe, gzip compressed backups are decompressed on the fly.
+// Returns error if file can not be read or has no version.
+func ReadSPOEFileVersion(path string) (int64, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return 0, conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", path))
+	}
+	p := &spoe.Parser{}
+	if err := p.ParseData(data); err != nil {
+		return 0, conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", path))
+	}
+	ver, err := p.Get("", parser.Comments, parser.CommentsSectionName, "# _version", false)
+	if err != nil {
+		return 0, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version: %s", err.Error()))
+	}
+	d, ok := ver.(*types.ConfigVersion)
+	if !ok {
+		return 0, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version of %s", path))
+	}
+	return d.Value, nil
+}
+
+// readFileHeaderVersion returns the version from the comments heading a SPOE
+// configuration file without parsing the rest of it
+func readFileHeaderVersion(path string) (int64, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return 0, conf.NewConfError(conf.ErrCannotReadConfFile, fmt.Sprintf("cannot read %s", path))
+	}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if v := strings.TrimPrefix(line, "# _version="); v != line {
+			version, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				break
+			}
+			return version, nil
+		}
+	}
+	return 0, conf.NewConfError(conf.ErrCannotReadVersion, fmt.Sprintf("cannot read version of %s", path))
+}
+
+// SubscribeVersionChanges returns a channel receiving the configuration version
+// each time it changes, either by IncrementVersion or by committing a transaction.
+// Channel holds one value, if the consumer is slow intermediate versions are
+// dropped and only the latest is kept. Channel is closed when ctx is done or
+// Close is called.
+func (c *SingleSpoe) SubscribeVersionChanges(ctx context.Context) (<-chan int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan int64, 1)
+	c.versionSubsMu.Lock()
+	if c.versionSubs == nil {
+		c.versionSubs = make(map[chan int64]struct{})
+	}
+	c.versionSubs[ch] = struct{}{}
+	c.versionSubsMu.Unlock()
+
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		select {
+		case <-ctx.Done():
+		case <-c.closing():
+		}
+		c.versionSubsMu.Lock()
+		delete(c.versionSubs, ch)
+		close(ch)
+		c.versionSubsMu.Unlock()
+	}()
+	return ch, nil
+}
+
+// notifyVersionChange sends current configuration version to all subscribers,
+// replacing a value not yet received
+func (c *SingleSpoe) notifyVersionChange() {
+	c.versionSubsMu.Lock()
+	defer c.versionSubsMu.Unlock()
+	if len(c.versionSubs) == 0 {
+		return
+	}
+	v, err := c.GetVersion("")
+	if err != nil {
+		return
+	}
+	for ch := range c.versionSubs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- v
+	}
+}
+
+// ListenForTransactionCommits returns a channel receiving a CommitInfo each time a
+// transaction is committed or the configuration replaced with ReplaceSPOEFile, and
+// filter, if not nil, returns true for it. Channel buffers commitSubBuffer values,
+// commits are dropped while the buffer is full. Channel is closed when ctx is done or Close is called.
+func (c *SingleSpoe) ListenForTransactionCommits(ctx context.Context, filter func(CommitInfo) bool) <-chan CommitInfo {
+	ch := make(chan CommitInfo, commitSubBuffer)
+	c.commitSubsMu.Lock()
+	if c.commitSubs == nil {
+		c.commitSubs = make(map[chan CommitInfo]func(CommitInfo) bool)
+	}
+	c.commitSubs[ch] = filter
+	c.commitSubsMu.Unlock()
+
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		select {
+		case <-ctx.Done():
+		case <-c.closing():
+		}
+		c.commitSubsMu.Lock()
+		delete(c.commitSubs, ch)
+		close(ch)
+		c.commitSubsMu.Unlock()
+	}()
+	return ch
+}
+
+// notifyCommit sends CommitInfo of a transaction replacing old with p as master
+// parser to all listeners whose filter accepts it
+func (c *SingleSpoe) notifyCommit(transactionID string, old, p *spoe.Parser) {
+	c.commitSubsMu.Lock()
+	defer c.commitSubsMu.Unlock()
+	if len(c.commitSubs) == 0 {
+		return
+	}
+	info := CommitInfo{
+		TransactionID: transactionID,
+		Scopes:        changedScopes(old, p),
+	}
+	info.Version, _ = c.GetVersion("")
+	for ch, filter := range c.commitSubs {
+		if filter != nil && !filter(info) {
+			continue
+		}
+		select {
+		case ch <- info:
+		default:
+		}
+	}
+}
+
+// changedScopes returns sorted names of scopes which differ between parsers
+func changedScopes(old, p *spoe.Parser) []string {
+	scopes := map[string]struct{}{}
+	for _, parsers := range []*spoe.Parser{old, p} {
+		if parsers == nil {
+			continue
+		}
+		for scope := range parsers.Parsers {
+			if parsers.IsScope(scope) {
+				scopes[scope] = struct{}{}
+			}
+		}
+	}
+	changed := []string{}
+	for scope := range scopes {
+		if old == nil || scopeText(old, scope) != scopeText(p, scope) {
+			changed = append(changed, scope)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -16,8 +16,11 @@
 package spoe
 
 import (
+	"context"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/haproxytech/client-native/v2/misc"
 )
@@ -74,3 +77,164 @@ func TestSingleSpoe_GetConfigurationVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestSingleSpoe_SubscribeVersionChanges(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := ss.SubscribeVersionChanges(ctx)
+	if err != nil {
+		t.Fatalf("SingleSpoe.SubscribeVersionChanges() error = %v", err)
+	}
+
+	// commit through implicit transaction, then increment directly without
+	// reading in between, only the latest version is kept
+	v, _ := ss.GetVersion("")
+	if err = ss.SetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "spoe-agents", "", v); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+	}
+	if err = ss.IncrementVersion(); err != nil {
+		t.Fatalf("SingleSpoe.IncrementVersion() error = %v", err)
+	}
+	select {
+	case got := <-ch:
+		if got != 3 {
+			t.Errorf("SingleSpoe.SubscribeVersionChanges() got = %v, want 3", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SingleSpoe.SubscribeVersionChanges() no version received")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("SingleSpoe.SubscribeVersionChanges() channel not closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("SingleSpoe.SubscribeVersionChanges() channel not closed")
+	}
+}
+
+func TestSingleSpoe_ListenForTransactionCommits(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	all := ss.ListenForTransactionCommits(ctx, nil)
+	other := ss.ListenForTransactionCommits(ctx, func(info CommitInfo) bool {
+		for _, scope := range info.Scopes {
+			if scope == "[other]" {
+				return true
+			}
+		}
+		return false
+	})
+
+	tr, err := ss.Transaction.StartTransaction(1)
+	if err != nil {
+		t.Fatalf("Transaction.StartTransaction() error = %v", err)
+	}
+	if err = ss.SetSPOEAgentUseBackend("[ip-reputation]", "iprep-agent", "spoe-agents", tr.ID, 0); err != nil {
+		t.Fatalf("SingleSpoe.SetSPOEAgentUseBackend() error = %v", err)
+	}
+	if _, err = ss.Transaction.CommitTransaction(tr.ID); err != nil {
+		t.Fatalf("Transaction.CommitTransaction() error = %v", err)
+	}
+
+	select {
+	case got := <-all:
+		want := CommitInfo{TransactionID: tr.ID, Version: 2, Scopes: []string{"[ip-reputation]"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SingleSpoe.ListenForTransactionCommits() got = %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SingleSpoe.ListenForTransactionCommits() no commit received")
+	}
+	select {
+	case got := <-other:
+		t.Errorf("SingleSpoe.ListenForTransactionCommits() got = %v, want filtered", got)
+	default:
+	}
+}
+
+func TestReadSPOEFileVersion(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	noVersionDir, noVersionFile, err := misc.CreateTempDir("[ip-reputation]\nspoe-agent iprep-agent\n", true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(noVersionFile)
+		_ = remove(noVersionDir)
+	}()
+	tests := []struct {
+		name    string
+		path    string
+		want    int64
+		wantErr bool
+	}{
+		{name: "Should read version", path: filepath.Join(dir, configFile), want: 1, wantErr: false},
+		{name: "Should fail on missing file", path: filepath.Join(dir, "missing.cfg"), wantErr: true},
+		{name: "Should fail on file without version", path: filepath.Join(noVersionDir, noVersionFile), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSPOEFileVersion(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadSPOEFileVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ReadSPOEFileVersion() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
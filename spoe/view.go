@@ -0,0 +1,122 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"fmt"
+
+	"github.com/haproxytech/config-parser/v3/spoe"
+
+	conf "github.com/haproxytech/client-native/v2/configuration"
+	"github.com/haproxytech/client-native/v2/models"
+)
+
+// ReadOnlyView is a read only view of the configuration at a given version
+type ReadOnlyView struct {
+	ss      *SingleSpoe
+	version int64
+}
+
+// ReadOnlyTransactionAtVersion returns a read only view of the configuration at version,
+// from the current configuration or from the backup file holding that version.
+// Returns error on fail or if there is no configuration with version.
+func (c *SingleSpoe) ReadOnlyTransactionAtVersion(version int64) (*ReadOnlyView, error) {
+	files := []string{c.Transaction.ConfigurationFile}
+	backups, err := c.backupFiles()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, backups...)
+
+	for _, file := range files {
+		data, err := readConfigFile(file)
+		if err != nil {
+			continue
+		}
+		view := newReadOnlyView(&spoe.Parser{})
+		if err := view.ss.Parser.ParseData(data); err != nil {
+			continue
+		}
+		if v, err := view.ss.GetVersion(""); err != nil || v != version {
+			continue
+		}
+		view.version = version
+		return view, nil
+	}
+	return nil, conf.NewConfError(conf.ErrObjectDoesNotExist, fmt.Sprintf("configuration with version %d does not exist", version))
+}
+
+func newReadOnlyView(p *spoe.Parser) *ReadOnlyView {
+	return &ReadOnlyView{
+		ss: &SingleSpoe{
+			Parser:               p,
+			parsers:              map[string]*spoe.Parser{},
+			transactionFileIndex: map[string]string{},
+		},
+	}
+}
+
+// Version returns the configuration version of the view
+func (v *ReadOnlyView) Version() int64 {
+	return v.version
+}
+
+// String returns the configuration of the view in writable form
+func (v *ReadOnlyView) String() string {
+	return v.ss.Parser.String()
+}
+
+// GetScopes returns configured scopes
+func (v *ReadOnlyView) GetScopes() (models.SpoeScopes, error) {
+	_, scopes, err := v.ss.GetScopes("")
+	return scopes, err
+}
+
+// GetAgents returns agents of scope
+func (v *ReadOnlyView) GetAgents(scope string) (models.SpoeAgents, error) {
+	_, agents, err := v.ss.GetAgents(scope, "")
+	return agents, err
+}
+
+// GetAgent returns a requested agent. Returns error if agent does not exist.
+func (v *ReadOnlyView) GetAgent(scope, name string) (*models.SpoeAgent, error) {
+	_, agent, err := v.ss.GetAgent(scope, name, "")
+	return agent, err
+}
+
+// GetGroups returns groups of scope
+func (v *ReadOnlyView) GetGroups(scope string) (models.SpoeGroups, error) {
+	_, groups, err := v.ss.GetGroups(scope, "")
+	return groups, err
+}
+
+// GetGroup returns a requested group. Returns error if group does not exist.
+func (v *ReadOnlyView) GetGroup(scope, name string) (*models.SpoeGroup, error) {
+	_, group, err := v.ss.GetGroup(scope, name, "")
+	return group, err
+}
+
+// GetMessages returns messages of scope
+func (v *ReadOnlyView) GetMessages(scope string) (models.SpoeMessages, error) {
+	_, messages, err := v.ss.GetMessages(scope, "")
+	return messages, err
+}
+
+// GetMessage returns a requested message. Returns error if message does not exist.
+func (v *ReadOnlyView) GetMessage(scope, name string) (*models.SpoeMessage, error) {
+	_, message, err := v.ss.GetMessage(scope, name, "")
+	return message, err
+}
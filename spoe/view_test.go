@@ -0,0 +1,78 @@
+// Copyright 2019 HAProxy Technologies
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package spoe
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haproxytech/client-native/v2/misc"
+)
+
+func TestSingleSpoe_ReadOnlyTransactionAtVersion(t *testing.T) {
+	dir, configFile, err := misc.CreateTempDir(basicConfig, true)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	transactionDir, _, err := misc.CreateTempDir("", false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer func() {
+		_ = remove(configFile)
+		_ = remove(dir)
+		_ = remove(transactionDir)
+	}()
+	params := Params{
+		SpoeDir:           dir,
+		TransactionDir:    transactionDir,
+		ConfigurationFile: filepath.Join(dir, configFile),
+		BackupsNumber:     3,
+	}
+	ss, err := newSingleSpoe(params)
+	if err != nil {
+		t.Fatalf("newSingleSpoe() error = %v", err)
+	}
+	if err = ss.DeleteAgent("[ip-reputation]", "iprep-agent", "", 1); err != nil {
+		t.Fatalf("SingleSpoe.DeleteAgent() error = %v", err)
+	}
+
+	view, err := ss.ReadOnlyTransactionAtVersion(1)
+	if err != nil {
+		t.Fatalf("SingleSpoe.ReadOnlyTransactionAtVersion() error = %v", err)
+	}
+	assert.Equal(t, int64(1), view.Version())
+	agent, err := view.GetAgent("[ip-reputation]", "iprep-agent")
+	if err != nil {
+		t.Errorf("ReadOnlyView.GetAgent() error = %v", err)
+	} else {
+		assert.Equal(t, "iprep-agent", *agent.Name)
+	}
+
+	view, err = ss.ReadOnlyTransactionAtVersion(2)
+	if err != nil {
+		t.Fatalf("SingleSpoe.ReadOnlyTransactionAtVersion() error = %v", err)
+	}
+	if _, err = view.GetAgent("[ip-reputation]", "iprep-agent"); err == nil {
+		t.Errorf("ReadOnlyView.GetAgent() error = nil, want error")
+	}
+
+	if _, err = ss.ReadOnlyTransactionAtVersion(5); err == nil {
+		t.Errorf("SingleSpoe.ReadOnlyTransactionAtVersion() error = nil, want error")
+	}
+}